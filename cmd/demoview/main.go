@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/pable/cs-demo-viewer/internal/demo"
 	"github.com/pable/cs-demo-viewer/internal/maps"
@@ -27,17 +33,83 @@ func uniqueOutPath(outDir, base string) string {
 	}
 }
 
+// parseRoundRange parses "N", "N-M", "N-" or "-M" into a first/last round pair (0 = open).
+func parseRoundRange(s string) (first, last int, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	lo, hi, isRange := strings.Cut(s, "-")
+	if !isRange {
+		hi = lo
+	}
+	if lo != "" {
+		if first, err = strconv.Atoi(lo); err != nil || first < 1 {
+			return 0, 0, fmt.Errorf("invalid round range %q", s)
+		}
+	}
+	if hi != "" {
+		if last, err = strconv.Atoi(hi); err != nil || last < 1 || last < first {
+			return 0, 0, fmt.Errorf("invalid round range %q", s)
+		}
+	}
+	return first, last, nil
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "maps" {
+		os.Exit(mapsCommand(os.Args[2:]))
+	}
+
 	out := flag.String("o", "", "output file (single mode) or output directory (dir mode); default: alongside input")
 	dir := flag.String("dir", "", "process all .dem files in this directory")
+	sample := flag.Int("sample", demo.DefaultSampleTicks, "ticks between sampled frames (16 = 4 fps at 64 tick, 4 = 16 fps, 32 = 2 fps)")
+	rounds := flag.String("rounds", "", "only record these rounds, e.g. 5, 5-12, 13- or -12")
+	noShots := flag.Bool("no-shots", false, "don't record weapon fire")
+	fullShots := flag.Bool("full-shots", false, "log every bullet with its hits (larger output)")
+	noTrails := flag.Bool("no-trails", false, "don't record grenade throw arcs")
+	noDamage := flag.Bool("no-damage", false, "don't record the per-hit damage log")
+	noUtility := flag.Bool("no-utility", false, "don't record smokes, flashes, HEs and molotovs")
+	timeout := flag.Duration("timeout", 0, "give up on a demo after this long, e.g. 2m (0 = no limit)")
+	mapsDir := flag.String("maps-dir", "", "load extra maps from Valve overview .txt files and radar images in this directory")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: demoview [flags] <demo.dem>\n")
-		fmt.Fprintf(os.Stderr, "       demoview -dir <directory> [-o <outdir>]\n\n")
+		fmt.Fprintf(os.Stderr, "       demoview -dir <directory> [-o <outdir>]\n")
+		fmt.Fprintf(os.Stderr, "       demoview maps validate <overview.txt>...\n\n")
 		fmt.Fprintf(os.Stderr, "Generates a self-contained HTML round-replay viewer from a CS2 demo.\n\n")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
 
+	firstRound, lastRound, err := parseRoundRange(*rounds)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reg := maps.NewRegistry()
+	if *mapsDir != "" {
+		n, err := reg.LoadDir(*mapsDir)
+		if err != nil && n == 0 {
+			log.Fatalf("maps dir: %v", err)
+		}
+		if err != nil {
+			log.Printf("maps dir: skipped: %v", err)
+		}
+		log.Printf("loaded %d map(s) from %s", n, *mapsDir)
+	}
+	opts := demo.ParseOptions{
+		SampleTicks: *sample,
+		SkipShots:   *noShots,
+		FullShots:   *fullShots,
+		SkipTrails:  *noTrails,
+		SkipDamage:  *noDamage,
+		SkipUtility: *noUtility,
+		FirstRound:  firstRound,
+		LastRound:   lastRound,
+	}
+
+	// Ctrl-C / SIGTERM stop the parse in progress cleanly.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	if *dir != "" {
 		// Bulk mode: process every .dem in the directory.
 		entries, err := os.ReadDir(*dir)
@@ -57,12 +129,16 @@ func main() {
 				continue
 			}
 			demoFile := filepath.Join(*dir, e.Name())
-			if err := processDemoFile(demoFile, outDir, true); err != nil {
+			if err := processDemoFile(ctx, demoFile, outDir, true, reg, opts, *timeout); err != nil {
 				log.Printf("SKIP %s: %v", e.Name(), err)
 				fail++
 			} else {
 				ok++
 			}
+			if ctx.Err() != nil {
+				log.Printf("interrupted")
+				break
+			}
 		}
 		log.Printf("done: %d succeeded, %d failed/skipped", ok, fail)
 		return
@@ -78,7 +154,7 @@ func main() {
 	if outputFile == "" {
 		outputFile = replaceExt(demoFile, ".html")
 	}
-	if err := processDemoTo(demoFile, outputFile); err != nil {
+	if err := processDemoTo(ctx, demoFile, outputFile, reg, opts, *timeout); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -86,37 +162,37 @@ func main() {
 // processDemoFile parses a demo and writes an HTML file.
 // In bulk mode the output filename is "<outDir>/<basename>_<mapname>.html".
 // In single mode outDir is ignored and the exact outputFile path is used instead.
-func processDemoFile(demoFile, outDir string, bulk bool) error {
+// The map's radar and metadata come from reg. A non-zero timeout bounds the
+// parse of this one demo.
+func processDemoFile(ctx context.Context, demoFile, outDir string, bulk bool, reg *maps.Registry, opts demo.ParseOptions, timeout time.Duration) error {
 	f, err := os.Open(demoFile)
 	if err != nil {
 		return fmt.Errorf("open: %w", err)
 	}
 	defer f.Close()
 
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	log.Printf("parsing %s ...", demoFile)
-	d, err := demo.Parse(f)
+	bar := newProgressBar(filepath.Base(demoFile))
+	opts.OnProgress = bar.update
+	d, err := demo.ParseContext(ctx, f, opts)
+	bar.clear()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("parse: timed out after %v", timeout)
+	}
 	if err != nil {
 		return fmt.Errorf("parse: %w", err)
 	}
 	log.Printf("  map: %s  rounds: %d  players: %d", d.MapName, len(d.Rounds), len(d.Players))
 
-	meta, ok := maps.GetMeta(d.MapName)
+	m, ok := reg.Get(d.MapName)
 	if !ok {
-		return fmt.Errorf("unsupported map %q", d.MapName)
-	}
-
-	radarPNG, err := maps.RadarPNG(d.MapName)
-	if err != nil {
-		return fmt.Errorf("radar PNG: %w", err)
-	}
-
-	lower, hasLower := maps.GetLower(d.MapName)
-	var radarLowerPNG []byte
-	if hasLower {
-		radarLowerPNG, err = maps.RadarPNGLower(d.MapName)
-		if err != nil {
-			return fmt.Errorf("lower radar PNG: %w", err)
-		}
+		return fmt.Errorf("unsupported map %q (add its overview with -maps-dir)", d.MapName)
 	}
 
 	var outputFile string
@@ -138,7 +214,7 @@ func processDemoFile(demoFile, outDir string, bulk bool) error {
 	}
 	defer out.Close()
 
-	if err := viewer.Write(out, d, meta, radarPNG, radarLowerPNG, lower, hasLower); err != nil {
+	if err := viewer.Write(out, d, m); err != nil {
 		return fmt.Errorf("generate HTML: %w", err)
 	}
 
@@ -147,8 +223,8 @@ func processDemoFile(demoFile, outDir string, bulk bool) error {
 }
 
 // processDemoTo is the single-file entry point with an explicit output path.
-func processDemoTo(demoFile, outputFile string) error {
-	return processDemoFile(demoFile, outputFile, false)
+func processDemoTo(ctx context.Context, demoFile, outputFile string, reg *maps.Registry, opts demo.ParseOptions, timeout time.Duration) error {
+	return processDemoFile(ctx, demoFile, outputFile, false, reg, opts, timeout)
 }
 
 func replaceExt(path, ext string) string {
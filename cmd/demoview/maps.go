@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pable/cs-demo-viewer/internal/maps"
+)
+
+// mapsCommand runs "demoview maps <subcommand>" and returns the exit code.
+func mapsCommand(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintf(os.Stderr, "Usage: demoview maps validate [-map name] <overview.txt>...\n")
+		return 2
+	}
+	return mapsValidate(args[1:])
+}
+
+// mapsValidate compares the embedded metadata of each overview file's map with
+// the file and prints the differences. It returns 1 if any file differs or
+// cannot be read.
+func mapsValidate(args []string) int {
+	fs := flag.NewFlagSet("maps validate", flag.ExitOnError)
+	mapName := fs.String("map", "", "compare against this embedded map instead of the file's top-level key")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: demoview maps validate [-map name] <overview.txt>...\n\n")
+		fmt.Fprintf(os.Stderr, "Diffs the embedded map metadata against Valve overview files\n")
+		fmt.Fprintf(os.Stderr, "(resource/overviews/<map>.txt).\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	reg := maps.NewRegistry()
+	status := 0
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		ov, err := maps.ParseOverview(data)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		name := ov.Name
+		if *mapName != "" {
+			name = *mapName
+		}
+		m, ok := reg.Get(name)
+		if !ok {
+			fmt.Printf("%s: no embedded map %q\n", path, name)
+			status = 1
+			continue
+		}
+		diffs := m.Diff(ov)
+		if len(diffs) == 0 {
+			fmt.Printf("%s: %s ok\n", path, name)
+			continue
+		}
+		status = 1
+		fmt.Printf("%s: %s differs\n", path, name)
+		for _, d := range diffs {
+			fmt.Printf("  %s\n", d)
+		}
+	}
+	return status
+}
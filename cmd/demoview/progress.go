@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pable/cs-demo-viewer/internal/demo"
+)
+
+// progressBar draws parse progress as a single self-overwriting line on stderr.
+// It stays silent when stderr is not a terminal, so scripted runs get clean logs.
+type progressBar struct {
+	label   string
+	tty     bool
+	lastLen int
+}
+
+func newProgressBar(label string) *progressBar {
+	fi, err := os.Stderr.Stat()
+	return &progressBar{label: label, tty: err == nil && fi.Mode()&os.ModeCharDevice != 0}
+}
+
+// update redraws the bar; it is used as demo.ParseOptions.OnProgress.
+func (b *progressBar) update(pr demo.Progress) {
+	if !b.tty {
+		return
+	}
+	const width = 30
+	var line string
+	if pr.TotalTicks > 0 {
+		f := pr.Fraction()
+		n := int(f * width)
+		line = fmt.Sprintf("%s [%s%s] %3.0f%%  round %d", b.label,
+			strings.Repeat("#", n), strings.Repeat(".", width-n), f*100, pr.Round)
+	} else {
+		line = fmt.Sprintf("%s  tick %d  round %d", b.label, pr.Tick, pr.Round)
+	}
+	fmt.Fprintf(os.Stderr, "\r%s%s", line, strings.Repeat(" ", max(0, b.lastLen-len(line))))
+	b.lastLen = len(line)
+}
+
+// clear erases the bar so the next log line starts on a clean line.
+func (b *progressBar) clear() {
+	if b.tty && b.lastLen > 0 {
+		fmt.Fprintf(os.Stderr, "\r%s\r", strings.Repeat(" ", b.lastLen))
+		b.lastLen = 0
+	}
+}
@@ -0,0 +1,280 @@
+package demo
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/pable/cs-demo-viewer/internal/maps"
+)
+
+// Kill.Tags bits, set by analyzeRound once a round is complete.
+const (
+	KillOpening = 1 << iota // first enemy kill of the round
+	KillTrade               // the victim had killed one of the killer's teammates within TradeSeconds
+	KillTraded              // the victim's killer was killed within TradeSeconds
+	KillClutch              // the killer was the last one alive on their side
+)
+
+// TradeSeconds is how soon after a death killing the killer still counts as a trade.
+const TradeSeconds = 5.0
+
+// ExecPlayers is how many Ts standing on one bombsite at once count as executing
+// it when the bomb is not planted.
+const ExecPlayers = 3
+
+// Clutch is a player left alone against one or more enemies, serialized as a
+// compact JSON array: [playerIdx, vs, tick, won(0/1), kills]
+// vs is the number of enemies alive when the player became the last of their side;
+// kills counts their kills from then on.
+type Clutch struct {
+	PlayerIdx int
+	Vs        int
+	Tick      int
+	Won       bool
+	Kills     int
+}
+
+func (c Clutch) MarshalJSON() ([]byte, error) {
+	won := 0
+	if c.Won {
+		won = 1
+	}
+	return json.Marshal([5]int{c.PlayerIdx, c.Vs, c.Tick, won, c.Kills})
+}
+
+// isFrag reports whether k is one player killing an enemy (not a team kill,
+// suicide, fall or bomb death).
+func isFrag(k Kill) bool {
+	return k.Cause == "" && !k.TeamKill && k.AtkIdx >= 0
+}
+
+// analyzeRound tags r's kills (opening, trade, traded, clutch, running multi-kill
+// count), finds the round's clutch, and adds the results to stats. Sides and the
+// players alive at the start come from the round's frames.
+func analyzeRound(r *Round, stats []PlayerStat, tradeTicks int) {
+	side := map[int]string{}
+	alive := map[string]int{}
+	live := map[int]bool{} // alive at the start and not yet killed
+	for fi, f := range r.Frames {
+		for _, ps := range f.Players {
+			if _, ok := side[ps.Idx]; ok {
+				continue
+			}
+			s := "CT"
+			if ps.Flags&(1<<1) != 0 {
+				s = "T"
+			}
+			side[ps.Idx] = s
+			if fi == 0 && ps.Flags&1 == 0 {
+				alive[s]++
+				live[ps.Idx] = true
+			}
+		}
+	}
+	stat := func(i int) *PlayerStat {
+		if i < 0 || i >= len(stats) {
+			return nil
+		}
+		return &stats[i]
+	}
+
+	kast := map[int]bool{} // players with a kill, assist or traded death this round
+	opened := false
+	multi := map[int]int{}
+	var clutch *Clutch
+	for i := range r.Kills {
+		k := &r.Kills[i]
+		if isFrag(*k) {
+			if !opened {
+				opened = true
+				k.Tags |= KillOpening
+				if st := stat(k.AtkIdx); st != nil {
+					st.OK++
+				}
+				if st := stat(k.VicIdx); st != nil {
+					st.OD++
+				}
+			}
+			// Avenging a teammate: the victim killed someone on the killer's side just before.
+			traded := false
+			for j := i - 1; j >= 0 && k.Tick-r.Kills[j].Tick <= tradeTicks; j-- {
+				prev := &r.Kills[j]
+				if !isFrag(*prev) || prev.AtkIdx != k.VicIdx || prev.Tags&KillTraded != 0 {
+					continue
+				}
+				prev.Tags |= KillTraded
+				kast[prev.VicIdx] = true
+				traded = true
+				if st := stat(prev.VicIdx); st != nil {
+					st.TRD++
+				}
+			}
+			if traded {
+				k.Tags |= KillTrade
+				if st := stat(k.AtkIdx); st != nil {
+					st.TRK++
+				}
+			}
+			kast[k.AtkIdx] = true
+			if k.AssisterIdx >= 0 {
+				kast[k.AssisterIdx] = true
+			}
+			multi[k.AtkIdx]++
+			k.Multi = multi[k.AtkIdx]
+			if clutch != nil && k.AtkIdx == clutch.PlayerIdx {
+				k.Tags |= KillClutch
+				clutch.Kills++
+			}
+		}
+
+		if !live[k.VicIdx] {
+			continue
+		}
+		delete(live, k.VicIdx)
+		s := side[k.VicIdx]
+		alive[s]--
+		// The first player left alone with enemies still standing is clutching.
+		if clutch == nil {
+			other := "T"
+			if s == "T" {
+				other = "CT"
+			}
+			if alive[s] == 1 && alive[other] > 0 {
+				for idx := range live {
+					if side[idx] == s {
+						clutch = &Clutch{PlayerIdx: idx, Vs: alive[other], Tick: k.Tick, Won: r.Winner == s}
+					}
+				}
+			}
+		}
+	}
+
+	// Survivors count towards KAST too; only players who started the round are rated.
+	for pi := range live {
+		kast[pi] = true
+	}
+	if len(r.Frames) > 0 {
+		for _, ps := range r.Frames[0].Players {
+			if st := stat(ps.Idx); st != nil && kast[ps.Idx] {
+				st.KAST++
+			}
+		}
+	}
+	for pi, n := range multi {
+		if st := stat(pi); st != nil && n >= 2 {
+			st.MK[min(n, 5)-2]++
+		}
+	}
+	if clutch != nil {
+		r.Clutch = clutch
+		if st := stat(clutch.PlayerIdx); st != nil {
+			vs := min(clutch.Vs, 5) - 1
+			st.CL[vs][0]++
+			if clutch.Won {
+				st.CL[vs][1]++
+			}
+		}
+	}
+}
+
+// tagSites sets r.SiteHit to the first bombsite a living T stood on after freeze
+// time and r.SiteExec to the site the bomb was planted on, or failing that the
+// first site ExecPlayers Ts stood on together. Positions come from the round's
+// frames, so a short visit between two samples can be missed.
+func tagSites(r *Round, regions []maps.Region) {
+	for _, b := range r.Bomb {
+		if b.Action == 1 && b.Site != "" {
+			r.SiteExec = b.Site
+			break
+		}
+	}
+	for _, f := range r.Frames {
+		if r.SiteHit != "" && r.SiteExec != "" {
+			break
+		}
+		if f.Tick < r.FreezeEnd {
+			continue
+		}
+		on := map[string]int{}
+		for _, ps := range f.Players {
+			if ps.Flags&1 != 0 || ps.Flags&(1<<1) == 0 {
+				continue
+			}
+			for _, reg := range regions {
+				if reg.Kind == maps.RegionSite && reg.Contains(float64(ps.X), float64(ps.Y), float64(ps.Z)) {
+					on[reg.Name]++
+					if r.SiteHit == "" {
+						r.SiteHit = reg.Name
+					}
+					if r.SiteExec == "" && on[reg.Name] >= ExecPlayers {
+						r.SiteExec = reg.Name
+					}
+				}
+			}
+		}
+	}
+	// The traced outlines are approximate; a plant still means the site was hit.
+	if r.SiteHit == "" {
+		r.SiteHit = r.SiteExec
+	}
+}
+
+// Average per-round rates that finishStats measures opening kills and multi-kill
+// rounds against: ten players share one opening kill a round, and the weighted
+// multi-kill rate is typical of competitive play.
+const (
+	avgOpeningKPR = 0.1
+	avgMultiKPR   = 0.2
+)
+
+// finishStats fills in the per-round rates and ratings from the accumulated counts.
+// Impact starts from the public least-squares fit of HLTV's impact and adds
+// opening kills and multi-kill rounds relative to an average player:
+//
+//	impact = 2.13·KPR + 0.42·APR − 0.41 + (OKPR − 0.1) + 0.5·(MKPR − 0.2)
+//
+// where APR is assists and OKPR opening kills per round, and MKPR weights rounds
+// with 2, 3, 4 and 5 kills by 1, 2, 3 and 4 per round. Rating is the public fit
+// of HLTV's rating 2.0:
+//
+//	rating = 0.0073·KAST% + 0.3591·KPR − 0.5329·DPR + 0.2372·impact + 0.0032·ADR + 0.1587
+//
+// Both land around 1.0 for an average player but do not reproduce HLTV's own
+// numbers.
+func finishStats(stats []PlayerStat) {
+	for i := range stats {
+		st := &stats[i]
+		if st.R == 0 {
+			continue
+		}
+		r := float64(st.R)
+		kpr, dpr, apr := float64(st.K)/r, float64(st.D)/r, float64(st.A)/r
+		adr := float64(st.DMG) / r
+		kastPct := 100 * float64(st.KAST) / r
+		multi := 0
+		for n, rounds := range st.MK {
+			multi += (n + 1) * rounds
+		}
+		okpr, mkpr := float64(st.OK)/r, float64(multi)/r
+		impact := 2.13*kpr + 0.42*apr - 0.41 + (okpr - avgOpeningKPR) + 0.5*(mkpr-avgMultiKPR)
+		rating := 0.0073*kastPct + 0.3591*kpr - 0.5329*dpr + 0.2372*impact + 0.0032*adr + 0.1587
+
+		st.ADR = round2(adr)
+		st.KPR = round2(kpr)
+		st.DPR = round2(dpr)
+		st.KASTPct = round2(kastPct)
+		st.Impact = round2(impact)
+		st.Rating = round2(rating)
+		if st.RTN > 0 {
+			st.ReactMs = round2(float64(st.RT) / float64(st.RTN))
+		}
+		if st.NT > 0 {
+			st.UDPN = round2(float64(st.UD) / float64(st.NT))
+		}
+	}
+}
+
+// round2 rounds to two decimals to keep the JSON short.
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,225 @@
+package demo
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/pable/cs-demo-viewer/internal/maps"
+)
+
+// testRound builds a round whose first frame has the ct and t players alive.
+func testRound(ct, t []int, winner string, kills ...Kill) *Round {
+	var f Frame
+	for _, i := range ct {
+		f.Players = append(f.Players, PlayerState{Idx: i})
+	}
+	for _, i := range t {
+		f.Players = append(f.Players, PlayerState{Idx: i, Flags: 1 << 1})
+	}
+	return &Round{Winner: winner, Frames: []Frame{f}, Kills: kills}
+}
+
+// frag is atk killing the enemy vic at tick, without an assist.
+func frag(tick, atk, vic int) Kill {
+	return Kill{Tick: tick, AtkIdx: atk, VicIdx: vic, AssisterIdx: -1}
+}
+
+func TestAnalyzeRound(t *testing.T) {
+	const tradeTicks = 320 // TradeSeconds at 64 tick
+	teamKill := frag(50, 1, 0)
+	teamKill.TeamKill = true
+
+	tests := []struct {
+		name   string
+		round  *Round
+		tags   []int
+		clutch *Clutch
+		stats  map[int]PlayerStat // players left out expect a zero stat line
+	}{
+		{
+			name:  "trade inside window",
+			round: testRound([]int{0, 1, 2}, []int{3, 4, 5}, "", frag(100, 3, 0), frag(100+tradeTicks, 1, 3)),
+			tags:  []int{KillOpening | KillTraded, KillTrade},
+			stats: map[int]PlayerStat{
+				0: {OD: 1, TRD: 1, KAST: 1}, // traded death
+				1: {TRK: 1, KAST: 1},
+				2: {KAST: 1},
+				3: {OK: 1, KAST: 1},
+				4: {KAST: 1},
+				5: {KAST: 1},
+			},
+		},
+		{
+			name:  "trade outside window",
+			round: testRound([]int{0, 1, 2}, []int{3, 4, 5}, "", frag(100, 3, 0), frag(101+tradeTicks, 1, 3)),
+			tags:  []int{KillOpening, 0},
+			stats: map[int]PlayerStat{
+				0: {OD: 1},
+				1: {KAST: 1},
+				2: {KAST: 1},
+				3: {OK: 1, KAST: 1},
+				4: {KAST: 1},
+				5: {KAST: 1},
+			},
+		},
+		{
+			name:   "team kill does not open",
+			round:  testRound([]int{0, 1, 2}, []int{3, 4, 5}, "", teamKill, frag(100, 3, 1)),
+			tags:   []int{0, KillOpening},
+			clutch: &Clutch{PlayerIdx: 2, Vs: 3, Tick: 100}, // the team kill counts towards it
+			stats: map[int]PlayerStat{
+				1: {OD: 1},
+				2: {KAST: 1, CL: [5][2]int{2: {1, 0}}},
+				3: {OK: 1, KAST: 1},
+				4: {KAST: 1},
+				5: {KAST: 1},
+			},
+		},
+		{
+			name:   "1v2 clutch won",
+			round:  testRound([]int{0, 1}, []int{2, 3}, "CT", frag(100, 2, 0), frag(1000, 1, 2), frag(2000, 1, 3)),
+			tags:   []int{KillOpening, KillClutch, KillClutch},
+			clutch: &Clutch{PlayerIdx: 1, Vs: 2, Tick: 100, Won: true, Kills: 2},
+			stats: map[int]PlayerStat{
+				0: {OD: 1},
+				1: {KAST: 1, MK: [4]int{1}, CL: [5][2]int{1: {1, 1}}},
+				2: {OK: 1, KAST: 1},
+			},
+		},
+		{
+			name:   "1v2 clutch lost",
+			round:  testRound([]int{0, 1}, []int{2, 3}, "T", frag(100, 2, 0), frag(1000, 1, 2), frag(2000, 3, 1)),
+			tags:   []int{KillOpening, KillClutch, 0},
+			clutch: &Clutch{PlayerIdx: 1, Vs: 2, Tick: 100, Won: false, Kills: 1},
+			stats: map[int]PlayerStat{
+				0: {OD: 1},
+				1: {KAST: 1, CL: [5][2]int{1: {1, 0}}},
+				2: {OK: 1, KAST: 1},
+				3: {KAST: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := make([]PlayerStat, 6)
+			analyzeRound(tt.round, stats, tradeTicks)
+
+			var tags []int
+			for _, k := range tt.round.Kills {
+				tags = append(tags, k.Tags)
+			}
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("kill tags = %v, want %v", tags, tt.tags)
+			}
+			if !reflect.DeepEqual(tt.round.Clutch, tt.clutch) {
+				t.Errorf("clutch = %+v, want %+v", tt.round.Clutch, tt.clutch)
+			}
+			for i, got := range stats {
+				if want := tt.stats[i]; !reflect.DeepEqual(got, want) {
+					t.Errorf("player %d stats = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// A league-average line should rate about 1.0, and opening kills and
+// multi-kills above average should raise impact.
+func TestFinishStatsAverage(t *testing.T) {
+	// 0.67 kills and deaths, 0.1 assists, 70% KAST and 73 ADR over 100 rounds,
+	// with average opening kills (0.1) and multi-kills (12 2Ks and 4 3Ks: 0.2).
+	avg := PlayerStat{K: 67, D: 67, A: 10, KAST: 70, DMG: 7300, R: 100, OK: 10, MK: [4]int{12, 4}}
+	strong := avg
+	strong.OK, strong.MK = 20, [4]int{12, 4, 2}
+
+	stats := []PlayerStat{avg, strong}
+	finishStats(stats)
+	st := stats[0]
+	if st.KPR != 0.67 || st.DPR != 0.67 || st.ADR != 73 || st.KASTPct != 70 {
+		t.Errorf("rates = KPR %v, DPR %v, ADR %v, KAST%% %v", st.KPR, st.DPR, st.ADR, st.KASTPct)
+	}
+	if math.Abs(st.Impact-1.06) > 0.01 {
+		t.Errorf("impact = %v, want 1.06", st.Impact)
+	}
+	if math.Abs(st.Rating-1.0) > 0.05 {
+		t.Errorf("rating = %v, want about 1.0", st.Rating)
+	}
+	// +0.1 opening kills and +0.06 weighted multi-kills per round.
+	if got := stats[1].Impact; math.Abs(got-1.19) > 0.01 {
+		t.Errorf("impact with more openings and multi-kills = %v, want 1.19", got)
+	}
+}
+
+func TestTagSites(t *testing.T) {
+	square := func(name string, x0 float64) maps.Region {
+		return maps.Region{Name: name, Kind: maps.RegionSite, ZMin: math.Inf(-1), ZMax: math.Inf(1),
+			Points: [][2]float64{{x0, 0}, {x0 + 100, 0}, {x0 + 100, 100}, {x0, 100}}}
+	}
+	regions := []maps.Region{square("A", 0), square("B", 1000)}
+	const onA, onB, off = 50, 1050, 500 // x positions
+	tp := func(x int) PlayerState { return PlayerState{Flags: 1 << 1, X: x, Y: 50} }
+	dead := PlayerState{Flags: 1<<1 | 1, X: onA, Y: 50}
+	ct := PlayerState{X: onB, Y: 50}
+	frame := func(tick int, ps ...PlayerState) Frame { return Frame{Tick: tick, Players: ps} }
+
+	tests := []struct {
+		name      string
+		round     Round
+		hit, exec string
+	}{
+		{
+			name: "plant decides exec",
+			round: Round{FreezeEnd: 100, Frames: []Frame{
+				frame(200, tp(onA), tp(off), tp(off)),
+				frame(300, tp(onB), tp(onB), tp(onB)),
+			}, Bomb: []BombAction{{Tick: 400, Action: 1, Site: "A"}}},
+			hit: "A", exec: "A",
+		},
+		{
+			name: "plant without a T seen on site",
+			round: Round{FreezeEnd: 100, Frames: []Frame{frame(200, tp(off))},
+				Bomb: []BombAction{{Tick: 0, Action: 0, Site: "B"}, {Tick: 400, Action: 1, Site: "B"}}},
+			hit: "B", exec: "B",
+		},
+		{
+			name: "ExecPlayers Ts on one site",
+			round: Round{FreezeEnd: 100, Frames: []Frame{
+				frame(200, tp(onB), tp(onB), tp(off)),
+				frame(300, tp(onA), tp(onA), tp(onA)),
+			}},
+			hit: "B", exec: "A",
+		},
+		{
+			name: "too few Ts to execute",
+			round: Round{FreezeEnd: 100, Frames: []Frame{
+				frame(200, tp(onA), tp(onA), tp(onB)),
+			}},
+			hit: "A", exec: "",
+		},
+		{
+			name: "unknown plant site falls back to frames",
+			round: Round{FreezeEnd: 100, Frames: []Frame{
+				frame(200, tp(onB), tp(onB), tp(onB)),
+			}, Bomb: []BombAction{{Tick: 400, Action: 1, Site: ""}}},
+			hit: "B", exec: "B",
+		},
+		{
+			name: "freeze time, dead Ts and CTs do not count",
+			round: Round{FreezeEnd: 100, Frames: []Frame{
+				frame(50, tp(onA), tp(onA), tp(onA)),
+				frame(200, dead, dead, dead, ct, tp(off)),
+			}},
+			hit: "", exec: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.round
+			tagSites(&r, regions)
+			if r.SiteHit != tt.hit || r.SiteExec != tt.exec {
+				t.Errorf("hit, exec = %q, %q, want %q, %q", r.SiteHit, r.SiteExec, tt.hit, tt.exec)
+			}
+		})
+	}
+}
@@ -1,28 +1,113 @@
 package demo
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"slices"
+	"strconv"
 
 	demoinfocs "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
 	common "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
 	"github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+
+	"github.com/pable/cs-demo-viewer/internal/maps"
 )
 
 func iround(f float64) int { return int(math.Round(f)) }
 
-// SampleTicks is how many ticks between sampled player-position frames.
-// At 64 ticks/sec, 16 ticks = 4 fps keyframes, interpolated to 60 fps in the viewer.
-const SampleTicks = 16
+// DefaultSampleTicks is how many ticks between sampled player-position frames
+// unless ParseOptions.SampleTicks overrides it.
+// At 64 ticks/sec, 16 ticks = 4 fps keyframes (8 fps at 128), interpolated to 60 fps in the viewer.
+const DefaultSampleTicks = 16
+
+// DefaultTickRate is assumed until the demo's own tick rate is known.
+const DefaultTickRate = 64
+
+// placeRadius is how close (in world units) a living player must be to a grenade
+// for their callout to name where it went off.
+const placeRadius = 400
+
+// Provisional lifetimes of lasting grenades, used until their expiry event arrives.
+const (
+	smokeSeconds   = 18
+	infernoSeconds = 7
+)
+
+// ParseOptions controls what ParseWithOptions records and how densely.
+// The zero value records everything at DefaultSampleTicks.
+type ParseOptions struct {
+	SampleTicks int  // ticks between sampled frames; <= 0 means DefaultSampleTicks
+	SkipShots   bool // don't record WeaponFire into Round.Shots (nor Round.ShotLog)
+	FullShots   bool // also record every bullet, with its hits, into Round.ShotLog
+	SkipTrails  bool // don't record grenade throw arcs
+	SkipDamage  bool // don't record the per-hit Round.Hits log (match DMG stats are still kept)
+	SkipUtility bool // don't record smokes, flashes, HEs, molotovs or fire areas
+	FirstRound  int  // first round number to record (1-based); 0 = from the start
+	LastRound   int  // last round number to record; 0 = to the end. Parsing stops after it.
+
+	// OnProgress, if set, is called roughly once per second of game time and once
+	// more when parsing finishes.
+	OnProgress func(Progress)
+}
+
+// Progress describes how far a parse has got.
+type Progress struct {
+	Tick       int // current in-game tick
+	TotalTicks int // from the demo header; 0 if the header doesn't say
+	Round      int // current round number (0 before the first live round)
+}
+
+// Fraction returns the parsed share of the demo in [0, 1], or 0 if TotalTicks is unknown.
+func (pr Progress) Fraction() float64 {
+	if pr.TotalTicks <= 0 {
+		return 0
+	}
+	return min(1, float64(pr.Tick)/float64(pr.TotalTicks))
+}
+
+func (o ParseOptions) sampleTicks() int {
+	if o.SampleTicks <= 0 {
+		return DefaultSampleTicks
+	}
+	return o.SampleTicks
+}
+
+func (o ParseOptions) keepRound(n int) bool {
+	return n >= o.FirstRound && (o.LastRound <= 0 || n <= o.LastRound)
+}
+
+// ErrStop can be returned by a ParseStream callback to stop parsing early.
+// ParseStream then returns the Summary so far and a nil error.
+var ErrStop = errors.New("demo: stop parsing")
+
+// Summary is the match-wide part of a parsed demo: everything except the rounds.
+type Summary struct {
+	MapName     string       `json:"map"`
+	TickRate    float64      `json:"tick_rate"`            // game ticks per second (64, 128, ...)
+	SampleTicks int          `json:"sample_ticks"`         // ticks between sampled frames
+	PSVersion   int          `json:"ps_version"`           // PlayerState layout, see PlayerStateVersion
+	RoundTime   float64      `json:"round_time,omitempty"` // seconds of play after freeze time (mp_roundtime_defuse / mp_roundtime)
+	C4Time      float64      `json:"c4_time,omitempty"`    // planted bomb fuse in seconds (mp_c4timer)
+	Teams       [2]Team      `json:"teams"`
+	Places      []string     `json:"places"` // callout string table; places[0] = "" (unknown)
+	Players     []PlayerInfo `json:"players"`
+	Stats       []PlayerStat `json:"stats"` // parallel to Players, indexed by player index
+}
 
 // DemoData is the full parsed representation of a demo.
 type DemoData struct {
-	MapName string       `json:"map"`
-	Players []PlayerInfo `json:"players"`
-	Rounds  []Round      `json:"rounds"`
-	Stats   []PlayerStat `json:"stats"` // parallel to Players, indexed by player index
+	Summary
+	Rounds []Round `json:"rounds"`
+}
+
+// Team is one of the two rosters, followed across halftime and overtime side swaps.
+type Team struct {
+	Name    string `json:"name"`    // clan name, or "Team A"/"Team B" if the demo has none
+	Players []int  `json:"players"` // indexes into Players of everyone who played for the team
 }
 
 // PlayerInfo is the static info for a player (referenced by index in frames/kills).
@@ -35,25 +120,77 @@ type PlayerInfo struct {
 type PlayerStat struct {
 	K   int `json:"k"`   // kills
 	D   int `json:"d"`   // deaths
+	A   int `json:"a"`   // assists (on enemy kills)
 	HS  int `json:"hs"`  // headshot kills
-	DMG int `json:"dmg"` // damage dealt (excluding team damage)
+	DMG int `json:"dmg"` // health taken off enemies (excluding team damage)
 	R   int `json:"r"`   // rounds played (for ADR = DMG/R)
+	TK  int `json:"tk"`  // teammates killed
+	TD  int `json:"td"`  // health damage dealt to teammates
+	SD  int `json:"sd"`  // health damage dealt to self (own grenades, molotovs)
+	EF  int `json:"ef"`  // enemies flashed
+	EBT int `json:"ebt"` // enemy blind time in milliseconds
+	TF  int `json:"tf"`  // teammates flashed (not counting self)
+	UD  int `json:"ud"`  // utility damage: HE and fire damage to enemies (also part of DMG)
+	NT  int `json:"nt"`  // HE and fire grenades thrown (damage per nade = UD/NT)
+	OK  int `json:"ok"`  // opening kills (first kill of the round)
+	OD  int `json:"od"`  // opening deaths
+	TRK int `json:"trk"` // trade kills
+	TRD int `json:"trd"` // deaths that were traded
+
+	MK   [4]int    `json:"mk"`   // rounds with 2, 3, 4 and 5 kills
+	CL   [5][2]int `json:"cl"`   // clutches by 1vN (N = 1..5): [attempts, won]
+	KAST int       `json:"kast"` // rounds with a kill, assist, survival or traded death
+	RT   int       `json:"rt"`   // summed reaction time in milliseconds (see Reaction)
+	RTN  int       `json:"rtn"`  // reactions summed in RT
+
+	// Per-round rates and ratings, derived from the counts above by finishStats.
+	ADR     float64 `json:"adr"`      // damage per round
+	KPR     float64 `json:"kpr"`      // kills per round
+	DPR     float64 `json:"dpr"`      // deaths per round
+	KASTPct float64 `json:"kast_pct"` // KAST / R as a percentage
+	Impact  float64 `json:"impact"`   // kills, assists, opening kills and multi-kills per round
+	Rating  float64 `json:"rating"`   // fitted rating 2.0 approximation, ~1.0 is average
+	ReactMs float64 `json:"react_ms"` // average reaction time, RT / RTN
+	UDPN    float64 `json:"udpn"`     // utility damage per HE/fire grenade thrown, UD / NT
+
+	Weapons map[string]*WeaponStat `json:"weapons,omitempty"` // per gun, by weapon name
+}
+
+// WeaponStat is one player's accuracy with one gun.
+// Accuracy is Hits/Shots; headshot-hit rate is HeadHits/Hits.
+type WeaponStat struct {
+	Shots    int `json:"shots"`     // bullets fired
+	Hits     int `json:"hits"`      // shots that hit an enemy
+	HeadHits int `json:"head_hits"` // shots that hit an enemy in the head
 }
 
 // Round contains all sampled frames and kills for one round.
 type Round struct {
-	Num       int          `json:"n"`
-	Winner    string       `json:"w"`              // "CT", "T", or ""
-	CTScore   int          `json:"cts"`             // CT score at START of this round
-	TScore    int          `json:"ts"`              // T score at START of this round
-	FreezeEnd int          `json:"fe"`              // tick when freeze time ended
-	Frames    []Frame      `json:"frames"`
-	Kills     []Kill       `json:"kills"`
-	Bomb      []BombAction `json:"bomb"`
-	Grenades  []Grenade    `json:"grenades"`
+	Num       int            `json:"n"`
+	Winner    string         `json:"w"`             // "CT", "T", or ""
+	Reason    string         `json:"why,omitempty"` // "elim", "bomb", "defuse", "time", "surrender", "draw"; "" if unknown
+	CTTeam    int            `json:"ct"`            // index into Teams of the team on CT (the other is on T)
+	Scores    [2]int         `json:"sc"`            // Teams[0] and Teams[1] score at START of this round
+	FreezeEnd int            `json:"fe"`            // tick when freeze time ended
+	Frames    []Frame        `json:"frames"`
+	Kills     []Kill         `json:"kills"`
+	Bomb      []BombAction   `json:"bomb"`
+	Grenades  []Grenade      `json:"grenades"`
 	Shots     []Shot         `json:"shots"`
-	Dmg       [][2]int       `json:"dmg,omitempty"`    // per-player damage: [playerIdx, healthDamage]
-	Trails    []GrenadeTrail `json:"trails,omitempty"` // grenade throw arcs
+	ShotLog   []ShotRecord   `json:"shotlog,omitempty"`  // every bullet, with ParseOptions.FullShots
+	Hits      []Hit          `json:"hits,omitempty"`     // damage to enemies, one entry per hit
+	TeamDmg   []FriendlyHit  `json:"tdmg,omitempty"`     // damage to teammates
+	SelfDmg   []FriendlyHit  `json:"sdmg,omitempty"`     // damage to oneself
+	Trails    []GrenadeTrail `json:"trails,omitempty"`   // grenade throw arcs
+	Infernos  []InfernoArea  `json:"infernos,omitempty"` // molotov/incendiary fire spread over time
+	Buys      []Buy          `json:"buys,omitempty"`     // purchases during buy time
+	Flashes   []Flash        `json:"flashes,omitempty"`  // flashbangs and who they blinded
+	Econ      [2]TeamEcon    `json:"econ"`               // per Teams entry, at freeze-time end
+	Clutch    *Clutch        `json:"clutch,omitempty"`   // the round's 1vX, if any
+	Contact   int            `json:"contact,omitempty"`  // tick an enemy was first spotted; 0 if never
+	Reactions []Reaction     `json:"react,omitempty"`    // per player, in order of first sighting
+	SiteHit   string         `json:"hit,omitempty"`      // first bombsite a T reached: "A", "B" or ""
+	SiteExec  string         `json:"exec,omitempty"`     // bombsite planted or executed on: "A", "B" or ""
 }
 
 // Frame is one sampled tick's snapshot of all player states.
@@ -62,10 +199,19 @@ type Frame struct {
 	Players []PlayerState `json:"p"`
 }
 
+// PlayerStateVersion identifies the PlayerState array layout; it is written to
+// Summary.PSVersion. Version 1 ended at money; version 2 added pitch, speed and armor
+// and flag bits 5-10; version 3 added seenBy, version 4 place.
+const PlayerStateVersion = 4
+
 // PlayerState is one player's state at a sampled tick, serialized as a compact JSON array:
-// [idx, flags, hp, x, y, z, yaw, weapon, utility, money]
-// flags bits: 0=dead, 1=T(vs CT), 2=bomb carrier, 3=has kevlar, 4=has helmet
+// [idx, flags, hp, x, y, z, yaw, weapon, utility, money, pitch, speed, armor, seenBy, place]
+// flags bits: 0=dead, 1=T(vs CT), 2=bomb carrier, 3=has kevlar, 4=has helmet,
+// 5=crouching, 6=walking (shift), 7=airborne, 8=scoped, 9=has defuse kit, 10=reloading
 // utility bits: 0=smoke, 1=HE, 2-3=flash count (0-2), 4=molotov/incendiary, 5=decoy
+// pitch: degrees, negative = looking up; speed: horizontal velocity in units/s
+// seenBy: bit j set if the player at position j of the same Frame.Players spots this
+// one (living enemies only); place: index into Summary.Places
 type PlayerState struct {
 	Idx     int
 	Flags   int
@@ -77,15 +223,26 @@ type PlayerState struct {
 	Weapon  string
 	Utility int
 	Money   int
+	Pitch   int
+	Speed   int
+	Armor   int
+	SeenBy  int
+	Place   int
 }
 
 func (ps PlayerState) MarshalJSON() ([]byte, error) {
-	return json.Marshal([]any{ps.Idx, ps.Flags, ps.HP, ps.X, ps.Y, ps.Z, ps.Yaw, ps.Weapon, ps.Utility, ps.Money})
+	return json.Marshal([]any{ps.Idx, ps.Flags, ps.HP, ps.X, ps.Y, ps.Z, ps.Yaw, ps.Weapon, ps.Utility, ps.Money, ps.Pitch, ps.Speed, ps.Armor, ps.SeenBy, ps.Place})
 }
 
 // Kill is serialized as a compact JSON array:
-// [tick, atkIdx, vicIdx, weapon, headshot(0/1), atkX, atkY, vicX, vicY, assisterIdx, flashAssist(0/1), noScope(0/1), throughSmoke(0/1), attackerBlind(0/1)]
+// [tick, atkIdx, vicIdx, weapon, headshot(0/1), atkX, atkY, vicX, vicY, assisterIdx, flashAssist(0/1), noScope(0/1), throughSmoke(0/1), attackerBlind(0/1), cause, teamKill(0/1), tags, multi, atkPlace, vicPlace]
 // assisterIdx: -1 if no assist; flashAssist: 1 if the assist was via flashbang
+// cause: "" for a player kill, else "world" (falls, map hazards), "bomb" or "suicide";
+// atkIdx is -1 for world and bomb deaths and the victim for suicides, whose
+// atkX/atkY are the victim's position.
+// tags: Kill* bits from analyzeRound; multi: the killer's kill count in the round so
+// far (1 for their first), 0 unless an enemy kill.
+// atkPlace/vicPlace: indexes into Summary.Places where the killer and victim stood.
 type Kill struct {
 	Tick          int
 	AtkIdx        int
@@ -101,6 +258,12 @@ type Kill struct {
 	NoScope       bool
 	ThroughSmoke  bool
 	AttackerBlind bool
+	Cause         string
+	TeamKill      bool
+	Tags          int
+	Multi         int
+	AtkPlace      int
+	VicPlace      int
 }
 
 func (k Kill) MarshalJSON() ([]byte, error) {
@@ -110,26 +273,76 @@ func (k Kill) MarshalJSON() ([]byte, error) {
 		}
 		return 0
 	}
-	return json.Marshal([]any{k.Tick, k.AtkIdx, k.VicIdx, k.Weapon, b(k.HS), k.AtkX, k.AtkY, k.VicX, k.VicY, k.AssisterIdx, b(k.FlashAssist), b(k.NoScope), b(k.ThroughSmoke), b(k.AttackerBlind)})
+	return json.Marshal([]any{k.Tick, k.AtkIdx, k.VicIdx, k.Weapon, b(k.HS), k.AtkX, k.AtkY, k.VicX, k.VicY, k.AssisterIdx, b(k.FlashAssist), b(k.NoScope), b(k.ThroughSmoke), b(k.AttackerBlind), k.Cause, b(k.TeamKill), k.Tags, k.Multi, k.AtkPlace, k.VicPlace})
 }
 
-// BombAction is serialized as a compact JSON array: [tick, action, x, y, site]
+// BombAction is serialized as a compact JSON array: [tick, action, x, y, site, place]
 // action: 0=plant_begin, 1=planted, 2=defuse_begin, 3=defused, 4=exploded, 5=dropped, 6=pickup
+// place: index into Summary.Places of the bomb's last known position
 type BombAction struct {
 	Tick   int
 	Action int
 	X      int
 	Y      int
 	Site   string
+	Place  int
 }
 
 func (b BombAction) MarshalJSON() ([]byte, error) {
-	return json.Marshal([]any{b.Tick, b.Action, b.X, b.Y, b.Site})
+	return json.Marshal([]any{b.Tick, b.Action, b.X, b.Y, b.Site, b.Place})
+}
+
+// Buy is a purchase during buy time, serialized as a compact JSON array: [tick, playerIdx, item]
+type Buy struct {
+	Tick int
+	PIdx int
+	Item string
+}
+
+func (b Buy) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{b.Tick, b.PIdx, b.Item})
+}
+
+// TeamEcon is one team's economy at freeze-time end.
+type TeamEcon struct {
+	Type    string   `json:"type"`    // "pistol", "eco", "force", "half", "full"; "" if nobody was playing
+	Value   int      `json:"value"`   // summed equipment value
+	Spent   int      `json:"spent"`   // money spent this round so far
+	Cash    int      `json:"cash"`    // money left
+	Players [][2]int `json:"players"` // [playerIdx, equipment value]
+}
+
+// Round-type thresholds, per player on average.
+const (
+	ecoMaxValue  = 1000 // equipment value below this: eco
+	fullMinValue = 4000 // at or above this: full buy
+	forceMaxCash = 1000 // in between: force buy if less money than this is left, else half-buy
+)
+
+// roundType classifies a team's buy from its equipment value and the money its
+// players kept. Pistol rounds are classified by the caller.
+func roundType(value, cash, players int) string {
+	if players == 0 {
+		return ""
+	}
+	switch {
+	case value/players < ecoMaxValue:
+		return "eco"
+	case value/players >= fullMinValue:
+		return "full"
+	case cash/players < forceMaxCash:
+		return "force"
+	}
+	return "half"
 }
 
-// Grenade is serialized as a compact JSON array: [startTick, endTick, type, x, y, throwerIdx]
+// Grenade is serialized as a compact JSON array:
+// [startTick, endTick, type, x, y, throwerIdx, enemyDmg, teamDmg, [victimIdx, ...], place]
 // type: 0=smoke, 1=flash, 2=HE, 3=molotov, 4=smoke-CT, 5=smoke-T; endTick=0 means instant
 // throwerIdx: index into Players slice (-1 if unknown)
+// enemyDmg/teamDmg: health damage the HE or fire did (teamDmg includes the thrower);
+// victims: everyone it hurt. Always 0, 0, [] for smokes and flashes.
+// place: index into Summary.Places where it went off (see placeNear)
 type Grenade struct {
 	StartTick  int
 	EndTick    int
@@ -137,10 +350,92 @@ type Grenade struct {
 	X          int
 	Y          int
 	ThrowerIdx int
+	EnemyDmg   int
+	TeamDmg    int
+	Victims    []int
+	Place      int
 }
 
 func (g Grenade) MarshalJSON() ([]byte, error) {
-	return json.Marshal([6]int{g.StartTick, g.EndTick, g.Type, g.X, g.Y, g.ThrowerIdx})
+	victims := g.Victims
+	if victims == nil {
+		victims = []int{}
+	}
+	return json.Marshal([]any{g.StartTick, g.EndTick, g.Type, g.X, g.Y, g.ThrowerIdx, g.EnemyDmg, g.TeamDmg, victims, g.Place})
+}
+
+// Flash is one flashbang and the players it blinded, serialized as a compact JSON array:
+// [tick, throwerIdx, x, y, [[vicIdx, blindMs], ...enemies], [[vicIdx, blindMs], ...teammates]]
+// The thrower flashing themselves counts as a teammate.
+type Flash struct {
+	Tick       int
+	ThrowerIdx int
+	X          int
+	Y          int
+	Enemies    [][2]int
+	Team       [][2]int
+}
+
+func (f Flash) MarshalJSON() ([]byte, error) {
+	en, tm := f.Enemies, f.Team
+	if en == nil {
+		en = [][2]int{}
+	}
+	if tm == nil {
+		tm = [][2]int{}
+	}
+	return json.Marshal([]any{f.Tick, f.ThrowerIdx, f.X, f.Y, en, tm})
+}
+
+// Reaction is one player's first sighting in a round, serialized as a compact JSON array:
+// [playerIdx, spotTick, seenTick, reactTick]
+// spotTick: the player first spotted an enemy; seenTick: an enemy first spotted them;
+// reactTick: their first gunshot or enemy damage within ReactSeconds of spotTick.
+// 0 = didn't happen.
+type Reaction struct {
+	PIdx      int
+	SpotTick  int
+	SeenTick  int
+	ReactTick int
+}
+
+func (r Reaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal([4]int{r.PIdx, r.SpotTick, r.SeenTick, r.ReactTick})
+}
+
+// ReactSeconds bounds a reaction: a first shot later than this after spotting
+// an enemy is not a response to them, and the sighting goes without a reaction.
+const ReactSeconds = 2.0
+
+// ShotRecord is one bullet fired, serialized as a compact JSON array:
+// [tick, playerIdx, weapon, x, y, z, yaw, pitch, [[vicIdx, hitgroup, hpDmg], ...]]
+// hitgroup: 0=generic, 1=head, 2=chest, 3=stomach, 4-5=arms, 6-7=legs, 8=neck, 10=gear
+type ShotRecord struct {
+	Tick   int
+	PIdx   int
+	Weapon string
+	X      int
+	Y      int
+	Z      int
+	Yaw    int
+	Pitch  int
+	Hits   [][3]int
+}
+
+func (s ShotRecord) MarshalJSON() ([]byte, error) {
+	hits := s.Hits
+	if hits == nil {
+		hits = [][3]int{}
+	}
+	return json.Marshal([]any{s.Tick, s.PIdx, s.Weapon, s.X, s.Y, s.Z, s.Yaw, s.Pitch, hits})
+}
+
+// firedShot is a player's latest bullet, waiting for the PlayerHurt events it causes.
+type firedShot struct {
+	tick      int
+	weapon    string
+	log       int // index into Round.ShotLog, -1 if not logged
+	hit, head bool
 }
 
 // Shot is serialized as a compact JSON array: [tick, playerIdx]
@@ -153,6 +448,42 @@ func (s Shot) MarshalJSON() ([]byte, error) {
 	return json.Marshal([2]int{s.Tick, s.PIdx})
 }
 
+// Hit is one instance of damage to an enemy, serialized as a compact JSON array:
+// [tick, atkIdx, vicIdx, weapon, hitgroup, hpDmg, armorDmg, atkX, atkY, vicX, vicY, penetrated]
+// hitgroup: as in ShotRecord; penetrated: objects the bullet went through, known
+// only for the killing hit (Kill.PenetratedObjects), else -1.
+type Hit struct {
+	Tick       int
+	AtkIdx     int
+	VicIdx     int
+	Weapon     string
+	HitGroup   int
+	HP         int
+	Armor      int
+	AtkX       int
+	AtkY       int
+	VicX       int
+	VicY       int
+	Penetrated int
+}
+
+func (h Hit) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{h.Tick, h.AtkIdx, h.VicIdx, h.Weapon, h.HitGroup, h.HP, h.Armor, h.AtkX, h.AtkY, h.VicX, h.VicY, h.Penetrated})
+}
+
+// FriendlyHit is damage to a teammate or to oneself, serialized as a compact
+// JSON array: [tick, atkIdx, vicIdx, weapon, hpDmg]. atkIdx == vicIdx for self-damage.
+type FriendlyHit struct {
+	Tick   int
+	AtkIdx int
+	VicIdx int
+	Weapon string
+	HP     int
+}
+
+func (h FriendlyHit) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{h.Tick, h.AtkIdx, h.VicIdx, h.Weapon, h.HP})
+}
 
 // GrenadeTrail is the throw arc of a grenade, serialized as: [startTick, endTick, type, throwerIdx, [[tickOffset,x,y],...]]
 // tickOffset is the elapsed ticks from startTick at each sampled point.
@@ -168,6 +499,123 @@ func (gt GrenadeTrail) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]any{gt.StartTick, gt.EndTick, gt.Type, gt.ThrowerIdx, gt.Points})
 }
 
+// InfernoArea is the burning area of one molotov/incendiary over time, serialized as:
+// [grenadeIdx, [[tick, [x0,y0,x1,y1,...]], ...]]
+// grenadeIdx: index into the round's Grenades (the type-3 entry this fire belongs to)
+// Each snapshot is the 2D convex hull of the fires still burning at that tick; a snapshot
+// is only recorded when the hull changes. An empty point list means no fire is burning.
+type InfernoArea struct {
+	GrenadeIdx int
+	Hulls      []FireHull
+}
+
+// FireHull is one convex-hull snapshot of an inferno's burning fires.
+type FireHull struct {
+	Tick   int
+	Points []int // flattened world x,y pairs
+}
+
+func (ia InfernoArea) MarshalJSON() ([]byte, error) {
+	hulls := make([][]any, len(ia.Hulls))
+	for i, h := range ia.Hulls {
+		pts := h.Points
+		if pts == nil {
+			pts = []int{}
+		}
+		hulls[i] = []any{h.Tick, pts}
+	}
+	return json.Marshal([]any{ia.GrenadeIdx, hulls})
+}
+
+// infernoTrack ties a live inferno entity to its InfernoArea in the current round.
+type infernoTrack struct {
+	inf *common.Inferno
+	idx int // index into Round.Infernos
+}
+
+// grenadeRef locates a Grenade whose end tick is still waiting on an expiry event.
+// Smokes and infernos routinely outlive RoundEnd, so the reference holds the
+// round itself rather than cur; parse never copies a round it has emitted.
+// References live until the next RoundStart.
+type grenadeRef struct {
+	round *Round
+	idx   int // index into round.Grenades
+}
+
+// spendMark tracks a player's MoneySpentThisRound across their pickups, so a
+// pickup can be told apart from a purchase.
+type spendMark struct {
+	tick   int // tick of the latest pickup
+	before int // spent before that tick
+	spent  int // spent as of that tick
+}
+
+// bombsiteName returns "A" or "B" for a bomb event's site, or "" if the site is unknown.
+func bombsiteName(s events.Bombsite) string {
+	switch s {
+	case events.BombsiteA:
+		return "A"
+	case events.BombsiteB:
+		return "B"
+	}
+	return ""
+}
+
+// isGun reports whether an equipment class fires bullets.
+func isGun(c common.EquipmentClass) bool {
+	return c == common.EqClassPistols || c == common.EqClassSMG || c == common.EqClassHeavy || c == common.EqClassRifle
+}
+
+// viewPitch returns a player's pitch in degrees, negative when looking up.
+func viewPitch(pl *common.Player) int {
+	pitch := float64(pl.ViewDirectionY())
+	if pitch > 180 {
+		pitch -= 360
+	}
+	return iround(pitch)
+}
+
+// roundEndReason maps a RoundEndReason to the Round.Reason string.
+// Returns "" for reasons that don't occur in bomb defusal.
+func roundEndReason(r events.RoundEndReason) string {
+	switch r {
+	case events.RoundEndReasonCTWin, events.RoundEndReasonTerroristsWin:
+		return "elim"
+	case events.RoundEndReasonTargetBombed:
+		return "bomb"
+	case events.RoundEndReasonBombDefused:
+		return "defuse"
+	case events.RoundEndReasonTargetSaved:
+		return "time"
+	case events.RoundEndReasonTerroristsSurrender, events.RoundEndReasonCTSurrender:
+		return "surrender"
+	case events.RoundEndReasonDraw:
+		return "draw"
+	}
+	return ""
+}
+
+// convarFloat returns a numeric convar, or 0 if it is unset or not a number.
+func convarFloat(cvars map[string]string, name string) float64 {
+	v, err := strconv.ParseFloat(cvars[name], 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
+
+// spots reports whether a has b in sight: both alive, on opposite sides, and b
+// flagged as spotted by a.
+func spots(a, b *common.Player) bool {
+	if a == nil || b == nil || a == b || !a.IsAlive() || !b.IsAlive() {
+		return false
+	}
+	if a.Team == b.Team || a.Team < common.TeamTerrorists || b.Team < common.TeamTerrorists {
+		return false
+	}
+	return b.IsSpottedBy(a)
+}
+
 // equipToGrenadeType maps equipment type to the Grenade type constant.
 // Returns -1 for non-tracked types.
 func equipToGrenadeType(t common.EquipmentType) int {
@@ -184,27 +632,128 @@ func equipToGrenadeType(t common.EquipmentType) int {
 	return -1
 }
 
-
-// Parse reads a CS2 demo from r and returns the structured DemoData.
+// Parse reads a CS2 demo from r and returns the structured DemoData
+// using the default ParseOptions.
 func Parse(r io.Reader) (*DemoData, error) {
+	return ParseWithOptions(r, ParseOptions{})
+}
+
+// ParseWithOptions reads a CS2 demo from r and returns the structured DemoData,
+// recording only what opts asks for.
+func ParseWithOptions(r io.Reader, opts ParseOptions) (*DemoData, error) {
+	return ParseContext(context.Background(), r, opts)
+}
+
+// ParseContext is ParseWithOptions with cancellation: parsing stops between
+// frames once ctx is done, and the context's error is returned.
+func ParseContext(ctx context.Context, r io.Reader, opts ParseOptions) (*DemoData, error) {
+	var rounds []*Round
+	sum, err := parse(ctx, r, opts, func(rd *Round) error {
+		rounds = append(rounds, rd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Copy only now, so expiry events that arrived after each RoundEnd are included.
+	data := &DemoData{Summary: *sum, Rounds: make([]Round, len(rounds))}
+	for i, rd := range rounds {
+		data.Rounds[i] = *rd
+	}
+	return data, nil
+}
+
+// ParseStream reads a CS2 demo from r and calls onRound with each round as soon
+// as its RoundEnd fires, so callers can write or index rounds without holding the
+// whole match in memory. Once the demo (or opts.LastRound) ends it returns the
+// match Summary. If onRound returns an error, parsing stops and that error is
+// returned; returning ErrStop stops parsing without an error. Cancelling ctx
+// stops parsing between frames and returns the context's error.
+//
+// Smokes and infernos still up at RoundEnd expire after the round has been handed
+// over, so their EndTick is provisional (never earlier than the round-end tick).
+func ParseStream(ctx context.Context, r io.Reader, opts ParseOptions, onRound func(Round) error) (*Summary, error) {
+	return parse(ctx, r, opts, func(rd *Round) error {
+		c := *rd
+		c.Grenades = slices.Clone(rd.Grenades) // detach from later expiry updates
+		return onRound(c)
+	})
+}
+
+// parse drives the demo and hands every kept round to emit at its RoundEnd.
+// The *Round remains owned by parse: late expiry events may still update its
+// grenades after emit returns, until the next round starts.
+func parse(ctx context.Context, r io.Reader, opts ParseOptions, emit func(*Round) error) (*Summary, error) {
 	p := demoinfocs.NewParser(r)
 	defer p.Close()
 
-	data := &DemoData{}
-	pidx := make(map[uint64]int) // steamID64 → Players index
+	sampleTicks := opts.sampleTicks()
+	data := &Summary{SampleTicks: sampleTicks, PSVersion: PlayerStateVersion, Places: []string{""}}
+	pidx := make(map[uint64]int)      // steamID64 → Players index
+	placeIdx := map[string]int{"": 0} // place name → Places index
 
 	var cur *Round
 	var inRound bool
 	var roundNum int
-	var freezeEndTick int  // only sample frames after freeze ends
-	var lastSampledTick int // deduplicate frames caused by full-snapshot packets
-	var ctScore, tScore int
-	lastShot := map[int]int{}            // playerIdx → last shot tick (dedup)
-	roundVicDmg := map[int]map[int]int{} // attIdx → vicIdx → accumulated hp-dmg this round
+	var freezeEndTick int                                       // only sample frames after freeze ends
+	var lastSampledTick int                                     // deduplicate frames caused by full-snapshot packets
+	var scores [2]int                                           // running score per Teams entry
+	ctTeam := 0                                                 // Teams index currently playing CT
+	teamOf := map[int]int{}                                     // playerIdx → Teams index
+	sidesKnown := false                                         // Teams[0] has been tied to a side
+	var skipRound bool                                          // current round is outside opts' round range
+	var done bool                                               // opts.LastRound has ended or emit failed; stop parsing
+	var emitErr error                                           // error returned by emit
+	lastShot := map[int]int{}                                   // playerIdx → last shot tick (dedup)
+	lastFire := map[int]firedShot{}                             // playerIdx → latest bullet, for hit attribution
+	roundVicDmg := map[int]map[int]int{}                        // attIdx → vicIdx → accumulated hp-dmg this round
 	pendingThrows := map[int64]struct{ tick, throwerIdx int }{} // grenade uniqueID → throw info
-	lastMolotovThrowerIdx := -1          // thrower of the most recent molotov projectile (for InfernoStart)
+	lastMolotovThrowerIdx := -1                                 // thrower of the most recent molotov projectile (for InfernoStart)
+	activeGrenades := map[int]grenadeRef{}                      // smoke/inferno entity ID → grenade awaiting its expiry event
+	infernos := map[int]infernoTrack{}                          // inferno entity ID → live inferno (fire area sampling)
+	flashByEntity := map[int]int{}                              // flashbang entity ID → index in cur.Flashes
+	reactionIdx := map[int]int{}                                // playerIdx → index in cur.Reactions
+	buySpent := map[int]spendMark{}                             // playerIdx → MoneySpentThisRound at their pickups
+	var lastSpotCheck int                                       // last tick checkSpotting ran
 	var bombX, bombY int
 	var bombSite string
+	var bombPlace int
+	bombExplodeTick := -1 // tick of this round's C4 explosion, -1 if none
+	buyTime := 20.0       // seconds of buy time after freeze end (mp_buytime)
+	maxRounds := 24       // regulation length (mp_maxrounds), for pistol rounds
+
+	// tickRate returns the demo's ticks per second. The parser learns it from the
+	// server info early in the demo; before that the header (or 64) stands in.
+	tickRate := func() float64 {
+		if tr := p.TickRate(); tr > 0 {
+			return tr
+		}
+		if h := p.Header(); h.PlaybackTicks > 0 && h.PlaybackTime > 0 {
+			return float64(h.PlaybackTicks) / h.PlaybackTime.Seconds()
+		}
+		return DefaultTickRate
+	}
+	// secondsToTicks converts a game-time duration in seconds to ticks.
+	secondsToTicks := func(secs float64) int { return iround(secs * tickRate()) }
+
+	// reportProgress calls opts.OnProgress at most once per second of game time,
+	// unless final is set.
+	lastReported := -1
+	reportProgress := func(final bool) {
+		if opts.OnProgress == nil {
+			return
+		}
+		tick := p.GameState().IngameTick()
+		if !final && lastReported >= 0 && tick-lastReported < secondsToTicks(1) {
+			return
+		}
+		lastReported = tick
+		total := p.Header().PlaybackTicks
+		if final && total > 0 {
+			tick = total
+		}
+		opts.OnProgress(Progress{Tick: tick, TotalTicks: total, Round: roundNum})
+	}
 
 	// getIdx returns the Players-slice index for a player, growing the slice if needed.
 	// data.Stats is kept parallel to data.Players.
@@ -227,6 +776,38 @@ func Parse(r io.Reader) (*DemoData, error) {
 		return i
 	}
 
+	// placeOf returns the index in data.Places of the callout pl is standing in,
+	// adding the name to the table the first time it is seen.
+	placeOf := func(pl *common.Player) int {
+		if pl == nil {
+			return 0
+		}
+		name := pl.LastPlaceName()
+		i, ok := placeIdx[name]
+		if !ok {
+			i = len(data.Places)
+			placeIdx[name] = i
+			data.Places = append(data.Places, name)
+		}
+		return i
+	}
+
+	// placeNear names a spot by the callout of the closest living player within
+	// placeRadius; place names only exist for players, not for arbitrary positions.
+	placeNear := func(x, y, z float64) int {
+		best, bestDist := 0, float64(placeRadius)
+		for _, pl := range p.GameState().Participants().Playing() {
+			if pl == nil || !pl.IsAlive() {
+				continue
+			}
+			pos := pl.Position()
+			if d := math.Sqrt((pos.X-x)*(pos.X-x) + (pos.Y-y)*(pos.Y-y) + (pos.Z-z)*(pos.Z-z)); d < bestDist {
+				best, bestDist = placeOf(pl), d
+			}
+		}
+		return best
+	}
+
 	captureFrame := func(tick int) Frame {
 		frame := Frame{Tick: tick}
 		bomb := p.GameState().Bomb()
@@ -234,10 +815,12 @@ func Parse(r io.Reader) (*DemoData, error) {
 		if bomb != nil && bomb.Carrier != nil {
 			carrierID = bomb.Carrier.SteamID64
 		}
+		var pls []*common.Player // parallel to frame.Players, for seenBy
 		for _, pl := range p.GameState().Participants().Playing() {
 			if pl == nil || pl.SteamID64 == 0 {
 				continue
 			}
+			pls = append(pls, pl)
 			pos := pl.Position()
 			flags := 2 // T+alive
 			if pl.Team == common.TeamCounterTerrorists {
@@ -255,6 +838,12 @@ func Parse(r io.Reader) (*DemoData, error) {
 					flags |= 16 // has helmet
 				}
 			}
+			for bit, on := range [...]bool{pl.IsDucking(), pl.IsWalking(), pl.IsAirborne(), pl.IsScoped(), pl.HasDefuseKit(), pl.IsReloading} {
+				if on {
+					flags |= 32 << bit // bits 5-10, see PlayerState
+				}
+			}
+			vel := pl.Velocity()
 			var wep string
 			if aw := pl.ActiveWeapon(); aw != nil && aw.Type != common.EqUnknown {
 				wep = aw.Type.String()
@@ -290,49 +879,342 @@ func Parse(r io.Reader) (*DemoData, error) {
 				Weapon:  wep,
 				Utility: util,
 				Money:   pl.Money(),
+				Pitch:   viewPitch(pl),
+				Speed:   iround(math.Hypot(vel.X, vel.Y)),
+				Armor:   pl.Armor(),
+				Place:   placeOf(pl),
 			})
 		}
+		for i, pl := range pls {
+			for j, other := range pls {
+				if spots(other, pl) {
+					frame.Players[i].SeenBy |= 1 << j
+				}
+			}
+		}
 		return frame
 	}
 
+	// reactionFor returns pi's Reaction in the current round, adding it on first use.
+	reactionFor := func(pi int) *Reaction {
+		i, ok := reactionIdx[pi]
+		if !ok {
+			i = len(cur.Reactions)
+			reactionIdx[pi] = i
+			cur.Reactions = append(cur.Reactions, Reaction{PIdx: pi})
+		}
+		return &cur.Reactions[i]
+	}
+
+	// checkSpotting records each player's first sighting of an enemy, and first
+	// being sighted, in the current round. Run every live tick so reaction times
+	// aren't quantized to the frame sampling interval.
+	checkSpotting := func(tick int) {
+		pls := p.GameState().Participants().Playing()
+		for _, pl := range pls {
+			for _, other := range pls {
+				if !spots(pl, other) {
+					continue
+				}
+				if cur.Contact == 0 {
+					cur.Contact = tick
+				}
+				if r := reactionFor(getIdx(pl)); r.SpotTick == 0 {
+					r.SpotTick = tick
+				}
+				if r := reactionFor(getIdx(other)); r.SeenTick == 0 {
+					r.SeenTick = tick
+				}
+			}
+		}
+	}
+
+	// reacted closes a player's reaction on their first gunshot or enemy damage
+	// after they spotted someone, if it came within ReactSeconds.
+	reacted := func(pi, tick int) {
+		i, ok := reactionIdx[pi]
+		if !ok {
+			return
+		}
+		r := &cur.Reactions[i]
+		if r.SpotTick == 0 || r.ReactTick != 0 || tick-r.SpotTick > secondsToTicks(ReactSeconds) {
+			return
+		}
+		r.ReactTick = tick
+		if pi >= 0 && pi < len(data.Stats) {
+			data.Stats[pi].RT += int(math.Round(float64(tick-r.SpotTick) * 1000 / tickRate()))
+			data.Stats[pi].RTN++
+		}
+	}
+
+	// sampleInfernos records the current fire hull of every burning inferno in cur.
+	sampleInfernos := func(tick int) {
+		if cur == nil {
+			return
+		}
+		for _, t := range infernos {
+			var pts []int
+			for _, pt := range t.inf.Fires().Active().ConvexHull2D() {
+				pts = append(pts, iround(pt.X), iround(pt.Y))
+			}
+			area := &cur.Infernos[t.idx]
+			if n := len(area.Hulls); n > 0 && slices.Equal(area.Hulls[n-1].Points, pts) {
+				continue
+			}
+			area.Hulls = append(area.Hulls, FireHull{Tick: tick, Points: pts})
+		}
+	}
+
+	// expireGrenade replaces the provisional end tick of a smoke or inferno with the
+	// tick its entity actually went away (expired, extinguished, or cleaned up).
+	expireGrenade := func(entityID int) {
+		ref, ok := activeGrenades[entityID]
+		if !ok {
+			return
+		}
+		delete(activeGrenades, entityID)
+		g := &ref.round.Grenades[ref.idx]
+		if tick := p.GameState().IngameTick(); tick >= g.StartTick {
+			g.EndTick = tick
+		}
+	}
+
+	// utilityGrenade finds the HE or fire (grenade type 2 or 3) in the current round
+	// that a player's utility damage came from: their latest one that has already
+	// gone off, as long as it is still live (HE damage lands on the explosion tick).
+	utilityGrenade := func(throwerIdx, gt, tick int) *Grenade {
+		for i := len(cur.Grenades) - 1; i >= 0; i-- {
+			g := &cur.Grenades[i]
+			if g.Type != gt || g.ThrowerIdx != throwerIdx || g.StartTick > tick {
+				continue
+			}
+			end := g.EndTick
+			if gt == 2 {
+				end = g.StartTick
+			}
+			if tick-end > secondsToTicks(0.5) {
+				return nil
+			}
+			return g
+		}
+		return nil
+	}
+
+	// assignSides works out which team is on CT. TeamState follows the side, not
+	// the team, so rosters are matched against the players seen on each team so
+	// far; the first live round puts Teams[0] on CT.
+	assignSides := func() {
+		gs := p.GameState()
+		sides := [2]*common.TeamState{gs.Team(common.TeamCounterTerrorists), gs.Team(common.TeamTerrorists)}
+		if sides[0] == nil || sides[1] == nil {
+			return
+		}
+		votes := 0 // > 0: CT roster looks like Teams[0]
+		for side, ts := range sides {
+			for _, pl := range ts.Members() {
+				t, ok := teamOf[getIdx(pl)]
+				switch {
+				case !ok:
+				case (t == 0) == (side == 0):
+					votes++
+				default:
+					votes--
+				}
+			}
+		}
+		switch {
+		case !sidesKnown:
+			ctTeam = 0
+		case votes > 0:
+			ctTeam = 0
+		case votes < 0:
+			ctTeam = 1
+		}
+		for side, ts := range sides {
+			team := ctTeam
+			if side == 1 {
+				team = 1 - ctTeam
+			}
+			if name := ts.ClanName(); name != "" {
+				data.Teams[team].Name = name
+			}
+			for _, pl := range ts.Members() {
+				if pl == nil {
+					continue
+				}
+				i := getIdx(pl)
+				teamOf[i] = team
+				sidesKnown = true
+				if !slices.Contains(data.Teams[team].Players, i) {
+					data.Teams[team].Players = append(data.Teams[team].Players, i)
+				}
+			}
+		}
+	}
+
+	// flashFor returns this round's Flash for a flashbang entity, adding it on
+	// first sight: PlayerFlashed and FlashExplode arrive in either order. Without
+	// an entity ID, the thrower's flash from the same moment is reused.
+	flashFor := func(entityID, tick int, thrower *common.Player) *Flash {
+		ti := getIdx(thrower)
+		if i, ok := flashByEntity[entityID]; ok && entityID >= 0 {
+			return &cur.Flashes[i]
+		}
+		if entityID < 0 {
+			for i := len(cur.Flashes) - 1; i >= 0; i-- {
+				if f := &cur.Flashes[i]; f.ThrowerIdx == ti && tick-f.Tick <= secondsToTicks(0.25) {
+					return f
+				}
+			}
+		} else {
+			flashByEntity[entityID] = len(cur.Flashes)
+		}
+		cur.Flashes = append(cur.Flashes, Flash{Tick: tick, ThrowerIdx: ti})
+		return &cur.Flashes[len(cur.Flashes)-1]
+	}
+
+	// countWin advances the running score and returns the winner as "CT", "T" or "".
+	countWin := func(t common.Team) string {
+		switch t {
+		case common.TeamCounterTerrorists:
+			scores[ctTeam]++
+			return "CT"
+		case common.TeamTerrorists:
+			scores[1-ctTeam]++
+			return "T"
+		}
+		return ""
+	}
+
 	p.RegisterEventHandler(func(e events.RoundStart) {
 		if p.GameState().IsWarmupPeriod() {
 			return
 		}
+		// The restart cleans up every smoke and fire, so no expiry is still to come
+		// for earlier rounds, emitted or abandoned; drop all their references.
+		clear(activeGrenades)
 		roundNum++
-		cur = &Round{Num: roundNum, CTScore: ctScore, TScore: tScore}
+		assignSides()
+		cur = &Round{Num: roundNum, CTTeam: ctTeam, Scores: scores}
+		infernos = map[int]infernoTrack{}
+		flashByEntity = map[int]int{}
+		reactionIdx = map[int]int{}
+		buySpent = map[int]spendMark{}
+		lastSpotCheck = 0
 		freezeEndTick = 0
 		lastSampledTick = 0
 		inRound = true
 		lastShot = map[int]int{}
+		lastFire = map[int]firedShot{}
 		roundVicDmg = map[int]map[int]int{}
 		pendingThrows = map[int64]struct{ tick, throwerIdx int }{}
 		lastMolotovThrowerIdx = -1
+		bombExplodeTick = -1
+		skipRound = !opts.keepRound(roundNum)
+		if skipRound {
+			// Outside the requested range: record nothing, but keep following the score.
+			cur = nil
+			inRound = false
+		}
 	})
 
 	p.RegisterEventHandler(func(e events.RoundFreezetimeEnd) {
+		if p.GameState().IsWarmupPeriod() {
+			return
+		}
+		// Players are surely on their new sides by now, even right after halftime.
+		assignSides()
 		if cur == nil {
 			return
 		}
+		cur.CTTeam = ctTeam
 		freezeEndTick = p.GameState().IngameTick()
 		cur.FreezeEnd = freezeEndTick
+		// The match config is in place by the first live round; keep the latest values.
+		cvars := p.GameState().Rules().ConVars()
+		if rt := convarFloat(cvars, "mp_roundtime_defuse"); rt > 0 {
+			data.RoundTime = rt * 60
+		} else if rt := convarFloat(cvars, "mp_roundtime"); rt > 0 {
+			data.RoundTime = rt * 60
+		}
+		if c4 := convarFloat(cvars, "mp_c4timer"); c4 > 0 {
+			data.C4Time = c4
+		}
+		if bt := convarFloat(cvars, "mp_buytime"); bt > 0 {
+			buyTime = bt
+		}
+		if mr := convarFloat(cvars, "mp_maxrounds"); mr > 0 {
+			maxRounds = int(mr)
+		}
+
+		// Economy: what each team takes into the round.
+		var players [2]int
+		for _, pl := range p.GameState().Participants().Playing() {
+			team := ctTeam
+			switch pl.Team {
+			case common.TeamCounterTerrorists:
+			case common.TeamTerrorists:
+				team = 1 - ctTeam
+			default:
+				continue
+			}
+			ec := &cur.Econ[team]
+			value := pl.EquipmentValueCurrent()
+			ec.Players = append(ec.Players, [2]int{getIdx(pl), value})
+			ec.Value += value
+			ec.Spent += pl.MoneySpentThisRound()
+			ec.Cash += pl.Money()
+			players[team]++
+		}
+		pistol := cur.Num == 1 || cur.Num == maxRounds/2+1
+		for team := range cur.Econ {
+			ec := &cur.Econ[team]
+			if ec.Type = roundType(ec.Value, ec.Cash, players[team]); pistol && ec.Type != "" {
+				ec.Type = "pistol"
+			}
+		}
 	})
 
-	p.RegisterEventHandler(func(e events.RoundEnd) {
-		if cur == nil {
+	p.RegisterEventHandler(func(e events.ItemPickup) {
+		if cur == nil || e.Player == nil || e.Weapon == nil || !e.Player.IsInBuyZone() {
 			return
 		}
-		switch e.Winner {
-		case common.TeamCounterTerrorists:
-			cur.Winner = "CT"
-		case common.TeamTerrorists:
-			cur.Winner = "T"
+		// A pickup in the buy zone during buy time counts as bought if the player's
+		// spending went up since their pickups of an earlier tick; a teammate's drop
+		// costs nothing. A buy bind hands out several items on one tick, so the
+		// comparison is against the spending before this tick.
+		tick := p.GameState().IngameTick()
+		if !p.GameState().IsFreezetimePeriod() && (freezeEndTick == 0 || tick > freezeEndTick+secondsToTicks(buyTime)) {
+			return
+		}
+		switch e.Weapon.Type {
+		case common.EqKnife, common.EqBomb, common.EqGlock, common.EqUSP, common.EqP2000:
+			return // handed out at spawn
+		}
+		pi := getIdx(e.Player)
+		m := buySpent[pi]
+		if m.tick != tick {
+			m.tick, m.before = tick, m.spent
+		}
+		m.spent = e.Player.MoneySpentThisRound()
+		buySpent[pi] = m
+		if m.spent <= m.before {
+			return
 		}
-		if cur.Winner == "CT" {
-			ctScore++
-		} else if cur.Winner == "T" {
-			tScore++
+		cur.Buys = append(cur.Buys, Buy{Tick: tick, PIdx: pi, Item: e.Weapon.Type.String()})
+	})
+
+	p.RegisterEventHandler(func(e events.RoundEnd) {
+		if cur == nil {
+			if skipRound {
+				countWin(e.Winner)
+				skipRound = false
+				done = opts.LastRound > 0 && roundNum >= opts.LastRound
+			}
+			return
 		}
+		cur.Winner = countWin(e.Winner)
+		cur.Reason = roundEndReason(e.Reason)
 		// Capture a final frame at the round-end tick so the last kill flash renders.
 		tick := p.GameState().IngameTick()
 		if f := captureFrame(tick); len(f.Players) > 0 {
@@ -348,25 +1230,63 @@ func Parse(r io.Reader) (*DemoData, error) {
 					}
 				}
 			}
-			data.Rounds = append(data.Rounds, *cur)
+			analyzeRound(cur, data.Stats, secondsToTicks(TradeSeconds))
+			tagSites(cur, maps.Regions(p.Header().MapName))
+			// Smokes and infernos still up will expire after the round is emitted;
+			// make their provisional end cover at least the rest of the round.
+			for _, ref := range activeGrenades {
+				if g := &ref.round.Grenades[ref.idx]; ref.round == cur && g.EndTick < tick {
+					g.EndTick = tick
+				}
+			}
+			if err := emit(cur); err != nil {
+				emitErr = err
+			}
+		} else {
+			for id, ref := range activeGrenades {
+				if ref.round == cur {
+					delete(activeGrenades, id)
+				}
+			}
 		}
 		cur = nil
 		inRound = false
+		done = emitErr != nil || (opts.LastRound > 0 && roundNum >= opts.LastRound)
 	})
 
 	p.RegisterEventHandler(func(e events.Kill) {
-		if cur == nil || e.Killer == nil || e.Victim == nil {
+		if cur == nil || e.Victim == nil {
 			return
 		}
 		tick := p.GameState().IngameTick()
-		ap := e.Killer.Position()
 		vp := e.Victim.Position()
 		var wep string
 		if e.Weapon != nil {
 			wep = e.Weapon.Type.String()
 		}
-		ai := getIdx(e.Killer)
 		vi := getIdx(e.Victim)
+		// Deaths without an enemy killer are kept too, tagged with their cause.
+		// C4 deaths come with no killer (or the victim) right after BombExplode.
+		var cause string
+		bombDeath := (e.Weapon != nil && e.Weapon.Type == common.EqBomb) ||
+			(bombExplodeTick >= 0 && tick-bombExplodeTick <= secondsToTicks(1) && (e.Killer == nil || e.Killer == e.Victim))
+		switch {
+		case bombDeath:
+			cause = "bomb"
+		case e.Killer == nil:
+			cause = "world"
+		case e.Killer == e.Victim:
+			cause = "suicide"
+		}
+		vPlace := placeOf(e.Victim)
+		ai, ap, aPlace := -1, vp, vPlace
+		switch cause {
+		case "":
+			ai, ap, aPlace = getIdx(e.Killer), e.Killer.Position(), placeOf(e.Killer)
+		case "suicide":
+			ai = vi
+		}
+		teamKill := cause == "" && e.Killer.Team == e.Victim.Team
 		asi := -1
 		if e.Assister != nil {
 			asi = getIdx(e.Assister)
@@ -386,13 +1306,33 @@ func Parse(r io.Reader) (*DemoData, error) {
 			NoScope:       e.NoScope,
 			ThroughSmoke:  e.ThroughSmoke,
 			AttackerBlind: e.AttackerBlind,
+			Cause:         cause,
+			TeamKill:      teamKill,
+			AtkPlace:      aPlace,
+			VicPlace:      vPlace,
 		})
-		// Accumulate match stats.
-		if ai >= 0 && ai < len(data.Stats) {
+		// The killing hit was just logged by PlayerHurt; only Kill knows its wallbang count.
+		if cause == "" {
+			for i := len(cur.Hits) - 1; i >= 0 && tick-cur.Hits[i].Tick <= 1; i-- {
+				if h := &cur.Hits[i]; h.AtkIdx == ai && h.VicIdx == vi {
+					h.Penetrated = e.PenetratedObjects
+					break
+				}
+			}
+		}
+		// Accumulate match stats. Only enemy kills count for the killer; every
+		// death counts for the victim.
+		if cause == "" && !teamKill && ai >= 0 && ai < len(data.Stats) {
 			data.Stats[ai].K++
 			if e.IsHeadshot {
 				data.Stats[ai].HS++
 			}
+			if asi >= 0 && asi < len(data.Stats) {
+				data.Stats[asi].A++
+			}
+		}
+		if teamKill && ai >= 0 && ai < len(data.Stats) {
+			data.Stats[ai].TK++
 		}
 		if vi >= 0 && vi < len(data.Stats) {
 			data.Stats[vi].D++
@@ -403,20 +1343,96 @@ func Parse(r io.Reader) (*DemoData, error) {
 		if cur == nil || e.Attacker == nil || e.Player == nil {
 			return
 		}
-		if e.Attacker.Team == e.Player.Team {
-			return // skip self and team damage
-		}
 		ai := getIdx(e.Attacker)
 		vi := getIdx(e.Player)
+		enemy := e.Attacker.Team != e.Player.Team
+		// Bullet hits land within a few ticks of the attacker's latest shot with the same gun.
+		if f, ok := lastFire[ai]; ok && e.Weapon != nil && e.Weapon.Type.String() == f.weapon &&
+			p.GameState().IngameTick()-f.tick <= secondsToTicks(0.05) {
+			if f.log >= 0 {
+				cur.ShotLog[f.log].Hits = append(cur.ShotLog[f.log].Hits, [3]int{vi, int(e.HitGroup), e.HealthDamageTaken})
+			}
+			if ws := data.Stats[ai].Weapons[f.weapon]; enemy && ws != nil {
+				if !f.hit {
+					ws.Hits++
+					f.hit = true
+				}
+				if e.HitGroup == events.HitGroupHead && !f.head {
+					ws.HeadHits++
+					f.head = true
+				}
+			}
+			lastFire[ai] = f
+		}
+		// HE and fire damage is credited to the grenade that did it.
+		if e.Weapon != nil {
+			if gt := equipToGrenadeType(e.Weapon.Type); gt == 2 || gt == 3 {
+				if enemy && ai >= 0 && ai < len(data.Stats) {
+					data.Stats[ai].UD += e.HealthDamageTaken
+				}
+				if g := utilityGrenade(ai, gt, p.GameState().IngameTick()); g != nil {
+					if enemy {
+						g.EnemyDmg += e.HealthDamageTaken
+					} else {
+						g.TeamDmg += e.HealthDamageTaken
+					}
+					if !slices.Contains(g.Victims, vi) {
+						g.Victims = append(g.Victims, vi)
+					}
+				}
+			}
+		}
+		if !enemy {
+			// Self and team damage are kept apart from damage to enemies.
+			if ai < 0 || ai >= len(data.Stats) {
+				return
+			}
+			hit := FriendlyHit{Tick: p.GameState().IngameTick(), AtkIdx: ai, VicIdx: vi, HP: e.HealthDamageTaken}
+			if e.Weapon != nil {
+				hit.Weapon = e.Weapon.Type.String()
+			}
+			if e.Attacker == e.Player {
+				data.Stats[ai].SD += e.HealthDamageTaken
+				if !opts.SkipDamage {
+					cur.SelfDmg = append(cur.SelfDmg, hit)
+				}
+			} else {
+				data.Stats[ai].TD += e.HealthDamageTaken
+				if !opts.SkipDamage {
+					cur.TeamDmg = append(cur.TeamDmg, hit)
+				}
+			}
+			return
+		}
+		reacted(ai, p.GameState().IngameTick())
 		if ai >= 0 && ai < len(data.Stats) {
-			data.Stats[ai].DMG += e.HealthDamage
-			cur.Dmg = append(cur.Dmg, [2]int{ai, e.HealthDamage})
+			data.Stats[ai].DMG += e.HealthDamageTaken
+			if !opts.SkipDamage {
+				ap, vp := e.Attacker.Position(), e.Player.Position()
+				hit := Hit{
+					Tick:       p.GameState().IngameTick(),
+					AtkIdx:     ai,
+					VicIdx:     vi,
+					HitGroup:   int(e.HitGroup),
+					HP:         e.HealthDamageTaken,
+					Armor:      e.ArmorDamageTaken,
+					AtkX:       iround(ap.X),
+					AtkY:       iround(ap.Y),
+					VicX:       iround(vp.X),
+					VicY:       iround(vp.Y),
+					Penetrated: -1,
+				}
+				if e.Weapon != nil {
+					hit.Weapon = e.Weapon.Type.String()
+				}
+				cur.Hits = append(cur.Hits, hit)
+			}
 		}
 		if ai >= 0 && vi >= 0 {
 			if roundVicDmg[ai] == nil {
 				roundVicDmg[ai] = map[int]int{}
 			}
-			roundVicDmg[ai][vi] += e.HealthDamage
+			roundVicDmg[ai][vi] += e.HealthDamageTaken
 		}
 	})
 
@@ -429,8 +1445,9 @@ func Parse(r io.Reader) (*DemoData, error) {
 		tick := p.GameState().IngameTick()
 		pos := e.Player.Position()
 		bombX, bombY = iround(pos.X), iround(pos.Y)
-		bombSite = string(rune(e.Site))
-		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 0, X: bombX, Y: bombY, Site: bombSite})
+		bombSite = bombsiteName(e.Site)
+		bombPlace = placeOf(e.Player)
+		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 0, X: bombX, Y: bombY, Site: bombSite, Place: bombPlace})
 	})
 
 	p.RegisterEventHandler(func(e events.BombPlanted) {
@@ -440,8 +1457,9 @@ func Parse(r io.Reader) (*DemoData, error) {
 		tick := p.GameState().IngameTick()
 		pos := e.Player.Position()
 		bombX, bombY = iround(pos.X), iround(pos.Y)
-		bombSite = string(rune(e.Site))
-		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 1, X: bombX, Y: bombY, Site: bombSite})
+		bombSite = bombsiteName(e.Site)
+		bombPlace = placeOf(e.Player)
+		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 1, X: bombX, Y: bombY, Site: bombSite, Place: bombPlace})
 	})
 
 	p.RegisterEventHandler(func(e events.BombDefuseStart) {
@@ -449,7 +1467,7 @@ func Parse(r io.Reader) (*DemoData, error) {
 			return
 		}
 		tick := p.GameState().IngameTick()
-		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 2, X: bombX, Y: bombY, Site: bombSite})
+		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 2, X: bombX, Y: bombY, Site: bombSite, Place: bombPlace})
 	})
 
 	p.RegisterEventHandler(func(e events.BombDefused) {
@@ -457,7 +1475,7 @@ func Parse(r io.Reader) (*DemoData, error) {
 			return
 		}
 		tick := p.GameState().IngameTick()
-		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 3, X: bombX, Y: bombY, Site: string(rune(e.Site))})
+		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 3, X: bombX, Y: bombY, Site: bombsiteName(e.Site), Place: bombPlace})
 	})
 
 	p.RegisterEventHandler(func(e events.BombExplode) {
@@ -465,7 +1483,8 @@ func Parse(r io.Reader) (*DemoData, error) {
 			return
 		}
 		tick := p.GameState().IngameTick()
-		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 4, X: bombX, Y: bombY, Site: bombSite})
+		bombExplodeTick = tick
+		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 4, X: bombX, Y: bombY, Site: bombSite, Place: bombPlace})
 	})
 
 	p.RegisterEventHandler(func(e events.BombDropped) {
@@ -475,7 +1494,8 @@ func Parse(r io.Reader) (*DemoData, error) {
 		tick := p.GameState().IngameTick()
 		pos := e.Player.Position()
 		bombX, bombY = iround(pos.X), iround(pos.Y)
-		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 5, X: bombX, Y: bombY, Site: bombSite})
+		bombPlace = placeOf(e.Player)
+		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 5, X: bombX, Y: bombY, Site: bombSite, Place: bombPlace})
 	})
 
 	p.RegisterEventHandler(func(e events.BombPickup) {
@@ -483,13 +1503,13 @@ func Parse(r io.Reader) (*DemoData, error) {
 			return
 		}
 		tick := p.GameState().IngameTick()
-		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 6, X: bombX, Y: bombY, Site: bombSite})
+		cur.Bomb = append(cur.Bomb, BombAction{Tick: tick, Action: 6, X: bombX, Y: bombY, Site: bombSite, Place: bombPlace})
 	})
 
 	// ── Grenade events ───────────────────────────────────────────────────────
 
 	p.RegisterEventHandler(func(e events.SmokeStart) {
-		if cur == nil {
+		if cur == nil || opts.SkipUtility {
 			return
 		}
 		tick := p.GameState().IngameTick()
@@ -501,18 +1521,24 @@ func Parse(r io.Reader) (*DemoData, error) {
 				smokeType = 5 // T smoke
 			}
 		}
+		activeGrenades[e.GrenadeEntityID] = grenadeRef{round: cur, idx: len(cur.Grenades)}
 		cur.Grenades = append(cur.Grenades, Grenade{
 			StartTick:  tick,
-			EndTick:    tick + 1152, // ~18 s at 64 ticks/s
+			EndTick:    tick + secondsToTicks(smokeSeconds), // provisional until SmokeExpired
 			Type:       smokeType,
 			X:          iround(e.Position.X),
 			Y:          iround(e.Position.Y),
 			ThrowerIdx: getIdx(e.Thrower),
+			Place:      placeNear(e.Position.X, e.Position.Y, e.Position.Z),
 		})
 	})
 
+	p.RegisterEventHandler(func(e events.SmokeExpired) {
+		expireGrenade(e.GrenadeEntityID)
+	})
+
 	p.RegisterEventHandler(func(e events.HeExplode) {
-		if cur == nil {
+		if cur == nil || opts.SkipUtility {
 			return
 		}
 		tick := p.GameState().IngameTick()
@@ -523,11 +1549,12 @@ func Parse(r io.Reader) (*DemoData, error) {
 			X:          iround(e.Position.X),
 			Y:          iround(e.Position.Y),
 			ThrowerIdx: getIdx(e.Thrower),
+			Place:      placeNear(e.Position.X, e.Position.Y, e.Position.Z),
 		})
 	})
 
 	p.RegisterEventHandler(func(e events.FlashExplode) {
-		if cur == nil {
+		if cur == nil || opts.SkipUtility {
 			return
 		}
 		tick := p.GameState().IngameTick()
@@ -538,24 +1565,82 @@ func Parse(r io.Reader) (*DemoData, error) {
 			X:          iround(e.Position.X),
 			Y:          iround(e.Position.Y),
 			ThrowerIdx: getIdx(e.Thrower),
+			Place:      placeNear(e.Position.X, e.Position.Y, e.Position.Z),
 		})
+		f := flashFor(e.GrenadeEntityID, tick, e.Thrower)
+		f.X, f.Y = iround(e.Position.X), iround(e.Position.Y)
+	})
+
+	p.RegisterEventHandler(func(e events.PlayerFlashed) {
+		if cur == nil || e.Player == nil || e.Attacker == nil {
+			return
+		}
+		ms := int(e.FlashDuration().Milliseconds())
+		ai, vi := getIdx(e.Attacker), getIdx(e.Player)
+		team := e.Attacker.Team == e.Player.Team
+		// Stats are kept even with SkipUtility; a self-flash counts for neither.
+		if ai >= 0 && ai < len(data.Stats) && e.Attacker != e.Player {
+			if team {
+				data.Stats[ai].TF++
+			} else {
+				data.Stats[ai].EF++
+				data.Stats[ai].EBT += ms
+			}
+		}
+		if opts.SkipUtility {
+			return
+		}
+		entityID := -1
+		if e.Projectile != nil && e.Projectile.Entity != nil {
+			entityID = e.Projectile.Entity.ID()
+		}
+		f := flashFor(entityID, p.GameState().IngameTick(), e.Attacker)
+		if f.X == 0 && f.Y == 0 && e.Projectile != nil {
+			pos := e.Projectile.Position()
+			f.X, f.Y = iround(pos.X), iround(pos.Y)
+		}
+		if team {
+			f.Team = append(f.Team, [2]int{vi, ms})
+		} else {
+			f.Enemies = append(f.Enemies, [2]int{vi, ms})
+		}
 	})
 
 	p.RegisterEventHandler(func(e events.InfernoStart) {
-		if cur == nil {
+		if cur == nil || opts.SkipUtility {
 			return
 		}
 		tick := p.GameState().IngameTick()
 		pos := e.Inferno.Entity.Position()
+		activeGrenades[e.Inferno.Entity.ID()] = grenadeRef{round: cur, idx: len(cur.Grenades)}
 		cur.Grenades = append(cur.Grenades, Grenade{
 			StartTick:  tick,
-			EndTick:    tick + 448, // ~7 s at 64 ticks/s
+			EndTick:    tick + secondsToTicks(infernoSeconds), // provisional until InfernoExpired
 			Type:       3,
 			X:          iround(pos.X),
 			Y:          iround(pos.Y),
 			ThrowerIdx: lastMolotovThrowerIdx, // set by GrenadeProjectileDestroy just before
+			Place:      placeNear(pos.X, pos.Y, pos.Z),
 		})
 		lastMolotovThrowerIdx = -1
+		cur.Infernos = append(cur.Infernos, InfernoArea{GrenadeIdx: len(cur.Grenades) - 1})
+		infernos[e.Inferno.Entity.ID()] = infernoTrack{inf: e.Inferno, idx: len(cur.Infernos) - 1}
+		sampleInfernos(tick)
+	})
+
+	p.RegisterEventHandler(func(e events.InfernoExpired) {
+		if e.Inferno == nil || e.Inferno.Entity == nil {
+			return
+		}
+		expireGrenade(e.Inferno.Entity.ID())
+		delete(infernos, e.Inferno.Entity.ID())
+	})
+
+	// inferno_expire game event; carries the inferno entity ID. Whichever of this
+	// and InfernoExpired arrives first settles the end tick.
+	p.RegisterEventHandler(func(e events.FireGrenadeExpired) {
+		expireGrenade(e.GrenadeEntityID)
+		delete(infernos, e.GrenadeEntityID)
 	})
 
 	// ── Grenade trajectory (throw arc) ──────────────────────────────────────
@@ -568,6 +1653,14 @@ func Parse(r io.Reader) (*DemoData, error) {
 		if e.Projectile.Thrower != nil {
 			pi = getIdx(e.Projectile.Thrower)
 		}
+		if wi := e.Projectile.WeaponInstance; wi != nil && pi >= 0 && pi < len(data.Stats) {
+			if gt := equipToGrenadeType(wi.Type); gt == 2 || gt == 3 {
+				data.Stats[pi].NT++
+			}
+		}
+		if opts.SkipTrails {
+			return
+		}
 		pendingThrows[e.Projectile.UniqueID()] = struct{ tick, throwerIdx int }{p.GameState().IngameTick(), pi}
 	})
 
@@ -610,7 +1703,7 @@ func Parse(r io.Reader) (*DemoData, error) {
 		points := make([][3]int, 0, 80)
 		for i := 0; i < len(traj); i += step {
 			te := traj[i]
-			tickOff := int(math.Round(te.Time.Seconds()*64)) - startTick
+			tickOff := secondsToTicks(te.Time.Seconds()) - startTick
 			if tickOff < 0 {
 				tickOff = 0
 			}
@@ -618,7 +1711,7 @@ func Parse(r io.Reader) (*DemoData, error) {
 		}
 		// Always include the final point
 		last := traj[len(traj)-1]
-		lastOff := int(math.Round(last.Time.Seconds()*64)) - startTick
+		lastOff := secondsToTicks(last.Time.Seconds()) - startTick
 		if lastOff < 0 {
 			lastOff = 0
 		}
@@ -634,7 +1727,7 @@ func Parse(r io.Reader) (*DemoData, error) {
 		})
 	})
 
-	// ── Weapon fire (deduplicated per player per SampleTicks window) ─────────
+	// ── Weapon fire (deduplicated per player per sample window) ──────────────
 
 	p.RegisterEventHandler(func(e events.WeaponFire) {
 		if cur == nil || e.Shooter == nil {
@@ -642,7 +1735,38 @@ func Parse(r io.Reader) (*DemoData, error) {
 		}
 		tick := p.GameState().IngameTick()
 		pi := getIdx(e.Shooter)
-		if last, ok := lastShot[pi]; ok && tick-last < SampleTicks {
+		if e.Weapon != nil && isGun(e.Weapon.Class()) && pi >= 0 && pi < len(data.Stats) {
+			wep := e.Weapon.Type.String()
+			st := &data.Stats[pi]
+			if st.Weapons == nil {
+				st.Weapons = map[string]*WeaponStat{}
+			}
+			if st.Weapons[wep] == nil {
+				st.Weapons[wep] = &WeaponStat{}
+			}
+			st.Weapons[wep].Shots++
+			f := firedShot{tick: tick, weapon: wep, log: -1}
+			if opts.FullShots && !opts.SkipShots {
+				pos := e.Shooter.Position()
+				f.log = len(cur.ShotLog)
+				cur.ShotLog = append(cur.ShotLog, ShotRecord{
+					Tick:   tick,
+					PIdx:   pi,
+					Weapon: wep,
+					X:      iround(pos.X),
+					Y:      iround(pos.Y),
+					Z:      iround(pos.Z),
+					Yaw:    iround(float64(e.Shooter.ViewDirectionX())),
+					Pitch:  viewPitch(e.Shooter),
+				})
+			}
+			lastFire[pi] = f
+			reacted(pi, tick)
+		}
+		if opts.SkipShots {
+			return
+		}
+		if last, ok := lastShot[pi]; ok && tick-last < sampleTicks {
 			return
 		}
 		cur.Shots = append(cur.Shots, Shot{Tick: tick, PIdx: pi})
@@ -650,10 +1774,14 @@ func Parse(r io.Reader) (*DemoData, error) {
 	})
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("parse demo: %w", err)
+		}
 		ok, err := p.ParseNextFrame()
 		if err != nil {
 			return nil, fmt.Errorf("parse demo: %w", err)
 		}
+		reportProgress(false)
 
 		if inRound && cur != nil {
 			tick := p.GameState().IngameTick()
@@ -661,19 +1789,35 @@ func Parse(r io.Reader) (*DemoData, error) {
 			// Also skip if tick hasn't advanced — full-snapshot (DEM_FullPacket) packets
 			// replay the same tick and would create duplicate frames, causing a periodic
 			// 1-frame freeze in playback every ~64 ticks (1 s).
-			if freezeEndTick > 0 && tick >= freezeEndTick && tick > lastSampledTick && tick%SampleTicks == 0 {
+			if freezeEndTick > 0 && tick >= freezeEndTick && tick > lastSampledTick && tick%sampleTicks == 0 {
 				if f := captureFrame(tick); len(f.Players) > 0 {
 					cur.Frames = append(cur.Frames, f)
 					lastSampledTick = tick
 				}
+				sampleInfernos(tick)
+			}
+			if freezeEndTick > 0 && tick >= freezeEndTick && tick > lastSpotCheck {
+				checkSpotting(tick)
+				lastSpotCheck = tick
 			}
 		}
 
-		if !ok {
+		if !ok || done {
 			break
 		}
 	}
 
+	if emitErr != nil && !errors.Is(emitErr, ErrStop) {
+		return nil, emitErr
+	}
+	reportProgress(true)
 	data.MapName = p.Header().MapName
+	data.TickRate = tickRate()
+	finishStats(data.Stats)
+	for i, name := range [2]string{"Team A", "Team B"} {
+		if data.Teams[i].Name == "" {
+			data.Teams[i].Name = name
+		}
+	}
 	return data, nil
 }
@@ -0,0 +1,175 @@
+package maps
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/bits"
+)
+
+// ddsToPNG converts the top mip level of a DDS texture to PNG. It handles the
+// formats radar images ship in: DXT1, DXT3, DXT5 and uncompressed 24/32-bit RGB(A).
+func ddsToPNG(data []byte) ([]byte, error) {
+	img, err := decodeDDS(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DDS header field offsets from the start of the file (the "DDS " magic included).
+const (
+	ddsHeight    = 12
+	ddsWidth     = 16
+	ddsPFFlags   = 80
+	ddsFourCC    = 84
+	ddsBitCount  = 88
+	ddsRMask     = 92 // then G, B and A masks
+	ddsHeaderEnd = 128
+)
+
+// DDS pixel format flags.
+const (
+	ddsPFAlpha  = 0x1
+	ddsPFFourCC = 0x4
+	ddsPFRGB    = 0x40
+)
+
+func decodeDDS(data []byte) (*image.NRGBA, error) {
+	if len(data) < ddsHeaderEnd || string(data[:4]) != "DDS " {
+		return nil, fmt.Errorf("not a DDS file")
+	}
+	u32 := func(off int) uint32 { return binary.LittleEndian.Uint32(data[off:]) }
+	w, h := int(u32(ddsWidth)), int(u32(ddsHeight))
+	if w <= 0 || h <= 0 || w > 16384 || h > 16384 {
+		return nil, fmt.Errorf("bad DDS size %dx%d", w, h)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	px := data[ddsHeaderEnd:]
+	flags := u32(ddsPFFlags)
+
+	if flags&ddsPFFourCC != 0 {
+		fourCC := string(data[ddsFourCC : ddsFourCC+4])
+		blockSize := 16
+		if fourCC == "DXT1" {
+			blockSize = 8
+		} else if fourCC != "DXT3" && fourCC != "DXT5" {
+			return nil, fmt.Errorf("unsupported DDS format %q", fourCC)
+		}
+		bw, bh := (w+3)/4, (h+3)/4
+		if len(px) < bw*bh*blockSize {
+			return nil, fmt.Errorf("DDS data truncated")
+		}
+		var block [16]color.NRGBA
+		for by := 0; by < bh; by++ {
+			for bx := 0; bx < bw; bx++ {
+				b := px[(by*bw+bx)*blockSize:]
+				switch fourCC {
+				case "DXT1":
+					dxtColors(b, true, &block)
+				case "DXT3":
+					dxtColors(b[8:], false, &block)
+					for i := range block {
+						a := b[i/2] >> (4 * (i % 2)) & 0xf
+						block[i].A = a<<4 | a
+					}
+				case "DXT5":
+					dxtColors(b[8:], false, &block)
+					dxt5Alpha(b, &block)
+				}
+				for i, c := range block {
+					if x, y := bx*4+i%4, by*4+i/4; x < w && y < h {
+						img.SetNRGBA(x, y, c)
+					}
+				}
+			}
+		}
+		return img, nil
+	}
+
+	if flags&ddsPFRGB == 0 {
+		return nil, fmt.Errorf("unsupported DDS pixel format")
+	}
+	bpp := int(u32(ddsBitCount)) / 8
+	if bpp != 3 && bpp != 4 {
+		return nil, fmt.Errorf("unsupported DDS bit count %d", bpp*8)
+	}
+	if len(px) < w*h*bpp {
+		return nil, fmt.Errorf("DDS data truncated")
+	}
+	masks := [4]uint32{u32(ddsRMask), u32(ddsRMask + 4), u32(ddsRMask + 8), u32(ddsRMask + 12)}
+	if flags&ddsPFAlpha == 0 {
+		masks[3] = 0
+	}
+	for i := 0; i < w*h; i++ {
+		var v uint32
+		for k := 0; k < bpp; k++ {
+			v |= uint32(px[i*bpp+k]) << (8 * k)
+		}
+		c := color.NRGBA{A: 255}
+		for k, ch := range [4]*uint8{&c.R, &c.G, &c.B, &c.A} {
+			if m := masks[k]; m != 0 {
+				*ch = uint8((v & m) >> bits.TrailingZeros32(m))
+			}
+		}
+		img.SetNRGBA(i%w, i/w, c)
+	}
+	return img, nil
+}
+
+// dxtColors decodes an 8-byte BC1 color block into out (row-major 4x4).
+// Only DXT1 uses the 3-color + transparent mode when c0 <= c1.
+func dxtColors(b []byte, dxt1 bool, out *[16]color.NRGBA) {
+	c0 := binary.LittleEndian.Uint16(b)
+	c1 := binary.LittleEndian.Uint16(b[2:])
+	var pal [4]color.NRGBA
+	pal[0], pal[1] = rgb565(c0), rgb565(c1)
+	mix := func(a, b uint8, wa, wb, d int) uint8 { return uint8((int(a)*wa + int(b)*wb) / d) }
+	if c0 > c1 || !dxt1 {
+		pal[2] = color.NRGBA{mix(pal[0].R, pal[1].R, 2, 1, 3), mix(pal[0].G, pal[1].G, 2, 1, 3), mix(pal[0].B, pal[1].B, 2, 1, 3), 255}
+		pal[3] = color.NRGBA{mix(pal[0].R, pal[1].R, 1, 2, 3), mix(pal[0].G, pal[1].G, 1, 2, 3), mix(pal[0].B, pal[1].B, 1, 2, 3), 255}
+	} else {
+		pal[2] = color.NRGBA{mix(pal[0].R, pal[1].R, 1, 1, 2), mix(pal[0].G, pal[1].G, 1, 1, 2), mix(pal[0].B, pal[1].B, 1, 1, 2), 255}
+		pal[3] = color.NRGBA{}
+	}
+	idx := binary.LittleEndian.Uint32(b[4:])
+	for i := range out {
+		out[i] = pal[idx>>(2*i)&3]
+	}
+}
+
+// dxt5Alpha decodes the 8-byte interpolated alpha block at the start of a DXT5 block.
+func dxt5Alpha(b []byte, out *[16]color.NRGBA) {
+	a0, a1 := int(b[0]), int(b[1])
+	var pal [8]uint8
+	pal[0], pal[1] = uint8(a0), uint8(a1)
+	if a0 > a1 {
+		for i := 1; i <= 6; i++ {
+			pal[i+1] = uint8(((7-i)*a0 + i*a1) / 7)
+		}
+	} else {
+		for i := 1; i <= 4; i++ {
+			pal[i+1] = uint8(((5-i)*a0 + i*a1) / 5)
+		}
+		pal[6], pal[7] = 0, 255
+	}
+	var idx uint64
+	for k := 0; k < 6; k++ {
+		idx |= uint64(b[2+k]) << (8 * k)
+	}
+	for i := range out {
+		out[i].A = pal[idx>>(3*i)&7]
+	}
+}
+
+func rgb565(c uint16) color.NRGBA {
+	r, g, b := uint8(c>>11&0x1f), uint8(c>>5&0x3f), uint8(c&0x1f)
+	return color.NRGBA{r<<3 | r>>2, g<<2 | g>>4, b<<3 | b>>2, 255}
+}
@@ -0,0 +1,107 @@
+package maps
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// ddsFile builds a DDS file with the given size, pixel format and data.
+// masks are the R, G, B and A bit masks of an uncompressed format.
+func ddsFile(w, h int, pfFlags uint32, fourCC string, bitCount uint32, masks [4]uint32, px []byte) []byte {
+	b := make([]byte, ddsHeaderEnd, ddsHeaderEnd+len(px))
+	copy(b, "DDS ")
+	binary.LittleEndian.PutUint32(b[ddsHeight:], uint32(h))
+	binary.LittleEndian.PutUint32(b[ddsWidth:], uint32(w))
+	binary.LittleEndian.PutUint32(b[ddsPFFlags:], pfFlags)
+	copy(b[ddsFourCC:], fourCC)
+	binary.LittleEndian.PutUint32(b[ddsBitCount:], bitCount)
+	for i, m := range masks {
+		binary.LittleEndian.PutUint32(b[ddsRMask+4*i:], m)
+	}
+	return append(b, px...)
+}
+
+func TestDecodeDXT1(t *testing.T) {
+	red, blue := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}
+	tests := []struct {
+		name  string
+		block []byte
+		want  map[[2]int]color.NRGBA
+	}{
+		{
+			// c0 > c1: two colors interpolated at 1/3 and 2/3. The first row uses
+			// indices 0-3, the other rows index 1.
+			name:  "four colors",
+			block: []byte{0x00, 0xf8, 0x1f, 0x00, 0xe4, 0x55, 0x55, 0x55},
+			want: map[[2]int]color.NRGBA{
+				{0, 0}: red,
+				{1, 0}: blue,
+				{2, 0}: {170, 0, 85, 255},
+				{3, 0}: {85, 0, 170, 255},
+				{0, 1}: blue,
+				{3, 3}: blue,
+			},
+		},
+		{
+			// c0 <= c1: index 2 is the midpoint and index 3 transparent.
+			name:  "three colors and transparent",
+			block: []byte{0x1f, 0x00, 0x00, 0xf8, 0xe4, 0xff, 0x00, 0x00},
+			want: map[[2]int]color.NRGBA{
+				{0, 0}: blue,
+				{1, 0}: red,
+				{2, 0}: {127, 0, 127, 255},
+				{3, 0}: {},
+				{0, 1}: {},
+				{0, 2}: blue,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := decodeDDS(ddsFile(4, 4, ddsPFFourCC, "DXT1", 0, [4]uint32{}, tt.block))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for p, want := range tt.want {
+				if got := img.NRGBAAt(p[0], p[1]); got != want {
+					t.Errorf("pixel %v = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeRGB24(t *testing.T) {
+	// Pixels are stored B, G, R, as the masks say.
+	masks := [4]uint32{0xff0000, 0x00ff00, 0x0000ff, 0}
+	px := []byte{0x10, 0x20, 0x30, 0xff, 0x00, 0x00, 0x00, 0x80, 0x00}
+	data, err := ddsToPNG(ddsFile(3, 1, ddsPFRGB, "", 24, masks, px))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x, want := range []color.NRGBA{{0x30, 0x20, 0x10, 255}, {0, 0, 255, 255}, {0, 0x80, 0, 255}} {
+		if got := color.NRGBAModel.Convert(img.At(x, 0)); got != want {
+			t.Errorf("pixel %d = %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestDecodeDDSErrors(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"not DDS":   []byte("\x89PNG\r\n\x1a\n"),
+		"truncated": ddsFile(8, 8, ddsPFFourCC, "DXT1", 0, [4]uint32{}, make([]byte, 8)),
+		"format":    ddsFile(4, 4, ddsPFFourCC, "ATI2", 0, [4]uint32{}, make([]byte, 16)),
+		"bit count": ddsFile(1, 1, ddsPFRGB, "", 16, [4]uint32{0xf800, 0x7e0, 0x1f, 0}, make([]byte, 2)),
+	} {
+		if _, err := decodeDDS(data); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
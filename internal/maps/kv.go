@@ -0,0 +1,158 @@
+package maps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeyValues is one node of a Valve KeyValues (VDF) document: a key with either a
+// string value or a block of child nodes. Keys are matched case-insensitively,
+// as the engine does.
+type KeyValues struct {
+	Key      string
+	Value    string
+	Children []*KeyValues
+}
+
+// Child returns the first child named key, or nil.
+func (kv *KeyValues) Child(key string) *KeyValues {
+	for _, c := range kv.Children {
+		if strings.EqualFold(c.Key, key) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Float returns the number stored under key.
+func (kv *KeyValues) Float(key string) (float64, error) {
+	c := kv.Child(key)
+	if c == nil {
+		return 0, fmt.Errorf("missing %q", key)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(c.Value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q: %w", key, err)
+	}
+	return v, nil
+}
+
+// ParseKeyValues parses a KeyValues text document into a root node (with an
+// empty key) holding its top-level entries. It accepts quoted and bare tokens,
+// the \" \\ \n \t escapes inside quotes, nested blocks, // comments, and
+// platform conditionals such as [$WIN32], which are skipped. #base and
+// #include directives are kept as plain entries; they are not followed.
+func ParseKeyValues(data []byte) (*KeyValues, error) {
+	toks, err := kvTokens(strings.TrimPrefix(string(data), "\ufeff")) // UTF-8 BOM
+	if err != nil {
+		return nil, err
+	}
+	root := &KeyValues{}
+	stack := []*KeyValues{root}
+	for i := 0; i < len(toks); i++ {
+		top := stack[len(stack)-1]
+		switch t := toks[i]; {
+		case t.brace == '}':
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("line %d: unexpected }", t.line)
+			}
+			stack = stack[:len(stack)-1]
+		case t.brace == '{':
+			return nil, fmt.Errorf("line %d: block without a key", t.line)
+		default:
+			n := &KeyValues{Key: t.text}
+			top.Children = append(top.Children, n)
+			if i+1 >= len(toks) {
+				return nil, fmt.Errorf("line %d: key %q has no value", t.line, t.text)
+			}
+			i++
+			switch next := toks[i]; next.brace {
+			case '{':
+				stack = append(stack, n)
+			case '}':
+				return nil, fmt.Errorf("line %d: key %q has no value", next.line, t.text)
+			default:
+				n.Value = next.text
+			}
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unclosed block %q", stack[len(stack)-1].Key)
+	}
+	return root, nil
+}
+
+type kvToken struct {
+	text  string
+	brace byte // '{' or '}' for a brace token, else 0
+	line  int
+}
+
+func kvTokens(s string) ([]kvToken, error) {
+	var toks []kvToken
+	line := 1
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '/' && i+1 < len(s) && s[i+1] == '/':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '[':
+			// Conditional such as [$WIN32] after a value; ignored.
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated conditional", line)
+			}
+			i += end + 1
+		case c == '{' || c == '}':
+			toks = append(toks, kvToken{brace: c, line: line})
+			i++
+		case c == '"':
+			var b strings.Builder
+			start := line
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				switch {
+				case s[j] == '\\' && j+1 < len(s):
+					j++
+					switch s[j] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case '"', '\\':
+						b.WriteByte(s[j])
+					default: // not an escape, e.g. a Windows path
+						b.WriteByte('\\')
+						b.WriteByte(s[j])
+					}
+				default:
+					if s[j] == '\n' {
+						line++
+					}
+					b.WriteByte(s[j])
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			toks = append(toks, kvToken{text: b.String(), line: start})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\r\n{}\"[", rune(s[j])) {
+				j++
+			}
+			toks = append(toks, kvToken{text: s[i:j], line: line})
+			i = j
+		}
+	}
+	return toks, nil
+}
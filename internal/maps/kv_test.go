@@ -0,0 +1,78 @@
+package maps
+
+import (
+	"os"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseKeyValues(t *testing.T) {
+	root, err := ParseKeyValues(readFixture(t, "de_nuke.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) != 2 {
+		t.Fatalf("top level has %d entries, want #base and de_nuke", len(root.Children))
+	}
+	if b := root.Children[0]; b.Key != "#base" || b.Value != "overviews/default.txt" {
+		t.Errorf("#base kept as %q = %q", b.Key, b.Value)
+	}
+	nuke := root.Child("DE_NUKE") // keys match case-insensitively
+	if nuke == nil {
+		t.Fatal("no de_nuke block")
+	}
+	for _, tt := range []struct{ key, want string }{
+		{"material", "overviews/de_nuke"}, // comment after the value dropped
+		{"pos_x", "-3453"},
+		{"rotate", "0"}, // bare tokens
+		{"zoom", "0"},
+		{"title", "Nuke \"Classic\"\tC:\\maps"}, // escapes; \m is not one
+		{"bombB_y", "0.58"},                     // [$WIN32] skipped
+	} {
+		c := nuke.Child(tt.key)
+		if c == nil {
+			t.Errorf("%s: missing", tt.key)
+			continue
+		}
+		if c.Value != tt.want {
+			t.Errorf("%s = %q, want %q", tt.key, c.Value, tt.want)
+		}
+	}
+	vs := nuke.Child("verticalsections")
+	if vs == nil || len(vs.Children) != 2 || vs.Children[1].Key != "lower" {
+		t.Fatalf("verticalsections = %+v", vs)
+	}
+	if v, err := vs.Children[1].Float("altitudemin"); err != nil || v != -10000 {
+		t.Errorf("lower AltitudeMin = %v, %v", v, err)
+	}
+	if _, err := nuke.Float("material"); err == nil {
+		t.Errorf("Float of a non-number: no error")
+	}
+	if _, err := nuke.Float("inset_left"); err == nil {
+		t.Errorf("Float of a missing key: no error")
+	}
+}
+
+func TestParseKeyValuesErrors(t *testing.T) {
+	for _, tt := range []struct{ name, src string }{
+		{"unterminated string", `"a" "b`},
+		{"unexpected brace", `"a" "b" }`},
+		{"unclosed block", `"a" { "b" "c"`},
+		{"key without value", `"a"`},
+		{"block without key", `{ "a" "b" }`},
+		{"key closing block", `"a" { "b" }`},
+		{"unterminated conditional", `"a" "b" [$WIN32`},
+	} {
+		if _, err := ParseKeyValues([]byte(tt.src)); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package maps
 
 import (
-	_ "embed"
 	"embed"
 	"fmt"
+	"math"
 )
 
 //go:embed overviews/*.png
@@ -16,13 +16,6 @@ type Meta struct {
 	Scale float64
 }
 
-// Lower holds metadata for the lower level of multi-floor maps.
-type Lower struct {
-	Meta
-	// ZMax is the world Z below which a player is considered on the lower level.
-	ZMax float64
-}
-
 var metas = map[string]Meta{
 	"de_ancient":  {PosX: -2953, PosY: 2164, Scale: 5.0},
 	"de_anubis":   {PosX: -2796, PosY: 3328, Scale: 5.22},
@@ -35,15 +28,18 @@ var metas = map[string]Meta{
 	"de_vertigo":  {PosX: -3168, PosY: 1762, Scale: 4.0},
 }
 
-// Lower level metadata for multi-floor maps.
-// PosX/PosY/Scale for lower levels are the same as upper in CS2 (shared radar space).
-var lowers = map[string]Lower{
-	// de_nuke lower: pit/lower bomb site is below z=-495
-	"de_nuke": {Meta: metas["de_nuke"], ZMax: -495},
-	// de_vertigo lower: scaffold level below z=11700
-	"de_vertigo": {Meta: metas["de_vertigo"], ZMax: 11700},
-	// de_train lower: underground below z=-130
-	"de_train": {Meta: metas["de_train"], ZMax: -130},
+// Vertical sections of multi-floor maps, highest first, as in the overview files'
+// verticalsections with the open ends as ±Inf. The "default" level's radar is
+// overviews/<map>.png, any other's overviews/<map>_<name>.png. All levels share
+// the map's PosX/PosY/Scale (CS2 draws them in the same radar space).
+// Maps not listed have a single unbounded "default" level.
+var sections = map[string][]Level{
+	// de_nuke: the pit/lower bomb site is below z=-495
+	"de_nuke": {{Name: "default", ZMin: -495, ZMax: math.Inf(1)}, {Name: "lower", ZMin: math.Inf(-1), ZMax: -495}},
+	// de_vertigo: the scaffold level is below z=11700
+	"de_vertigo": {{Name: "default", ZMin: 11700, ZMax: math.Inf(1)}, {Name: "lower", ZMin: math.Inf(-1), ZMax: 11700}},
+	// de_train: the underground is below z=-130
+	"de_train": {{Name: "default", ZMin: -130, ZMax: math.Inf(1)}, {Name: "lower", ZMin: math.Inf(-1), ZMax: -130}},
 }
 
 // GetMeta returns coordinate metadata for a map. Second return is false if unknown.
@@ -52,13 +48,7 @@ func GetMeta(mapName string) (Meta, bool) {
 	return m, ok
 }
 
-// GetLower returns lower-level metadata for multi-floor maps.
-func GetLower(mapName string) (Lower, bool) {
-	l, ok := lowers[mapName]
-	return l, ok
-}
-
-// RadarPNG returns the PNG bytes for the upper radar of mapName.
+// RadarPNG returns the PNG bytes for the main ("default" level) radar of mapName.
 func RadarPNG(mapName string) ([]byte, error) {
 	path := fmt.Sprintf("overviews/%s.png", mapName)
 	b, err := overviewFS.ReadFile(path)
@@ -68,12 +58,10 @@ func RadarPNG(mapName string) ([]byte, error) {
 	return b, nil
 }
 
-// RadarPNGLower returns the PNG bytes for the lower-level radar of mapName.
-// Returns nil, nil if the map has no lower level.
-func RadarPNGLower(mapName string) ([]byte, error) {
-	if _, ok := lowers[mapName]; !ok {
-		return nil, nil
+// levelRadarPNG returns the PNG bytes for the radar of one of mapName's levels.
+func levelRadarPNG(mapName, level string) ([]byte, error) {
+	if level == "default" {
+		return RadarPNG(mapName)
 	}
-	path := fmt.Sprintf("overviews/%s_lower.png", mapName)
-	return overviewFS.ReadFile(path)
+	return overviewFS.ReadFile(fmt.Sprintf("overviews/%s_%s.png", mapName, level))
 }
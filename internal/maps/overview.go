@@ -0,0 +1,116 @@
+package maps
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Overview is the content of a Valve overview file (resource/overviews/<map>.txt):
+// the radar's coordinate metadata and its vertical sections. Level radars are
+// not part of the file and are left nil.
+type Overview struct {
+	Name   string // the file's top-level key, e.g. "de_nuke"
+	Meta   Meta
+	Levels []Level // highest first; a single unbounded "default" level without verticalsections
+}
+
+// ParseOverview parses an overview file. Valve marks the open ends of the top
+// and bottom sections with large altitudes (±10000 or so); those become ±Inf.
+func ParseOverview(data []byte) (*Overview, error) {
+	root, err := ParseKeyValues(data)
+	if err != nil {
+		return nil, err
+	}
+	// The first top-level block is the overview; #base and #include lines are not.
+	var kv *KeyValues
+	for _, c := range root.Children {
+		if !strings.HasPrefix(c.Key, "#") {
+			kv = c
+			break
+		}
+	}
+	if kv == nil {
+		return nil, fmt.Errorf("no overview block")
+	}
+	ov := &Overview{Name: kv.Key}
+	if ov.Meta.PosX, err = kv.Float("pos_x"); err != nil {
+		return nil, err
+	}
+	if ov.Meta.PosY, err = kv.Float("pos_y"); err != nil {
+		return nil, err
+	}
+	if ov.Meta.Scale, err = kv.Float("scale"); err != nil {
+		return nil, err
+	}
+	if ov.Meta.Scale <= 0 {
+		return nil, fmt.Errorf("scale must be positive, got %v", ov.Meta.Scale)
+	}
+
+	vs := kv.Child("verticalsections")
+	if vs == nil || len(vs.Children) == 0 {
+		ov.Levels = []Level{{Name: "default", ZMin: math.Inf(-1), ZMax: math.Inf(1)}}
+		return ov, nil
+	}
+	for _, sec := range vs.Children {
+		l := Level{Name: sec.Key}
+		if l.ZMin, err = sec.Float("AltitudeMin"); err != nil {
+			return nil, fmt.Errorf("verticalsections %q: %w", sec.Key, err)
+		}
+		if l.ZMax, err = sec.Float("AltitudeMax"); err != nil {
+			return nil, fmt.Errorf("verticalsections %q: %w", sec.Key, err)
+		}
+		if l.ZMin >= l.ZMax {
+			return nil, fmt.Errorf("verticalsections %q: AltitudeMin %v is not below AltitudeMax %v", sec.Key, l.ZMin, l.ZMax)
+		}
+		ov.Levels = append(ov.Levels, l)
+	}
+	sort.SliceStable(ov.Levels, func(i, j int) bool { return ov.Levels[i].ZMax > ov.Levels[j].ZMax })
+	ov.Levels[0].ZMax = math.Inf(1)
+	ov.Levels[len(ov.Levels)-1].ZMin = math.Inf(-1)
+	return ov, nil
+}
+
+// Diff lists how m differs from ov in coordinate metadata and level altitude
+// ranges, one line per difference; empty if they agree.
+func (m *Map) Diff(ov *Overview) []string {
+	var diffs []string
+	for _, f := range []struct {
+		name      string
+		have, got float64
+	}{
+		{"pos_x", m.Meta.PosX, ov.Meta.PosX},
+		{"pos_y", m.Meta.PosY, ov.Meta.PosY},
+		{"scale", m.Meta.Scale, ov.Meta.Scale},
+	} {
+		if math.Abs(f.have-f.got) > 1e-6 {
+			diffs = append(diffs, fmt.Sprintf("%s: have %v, file has %v", f.name, f.have, f.got))
+		}
+	}
+
+	levels := map[string]Level{}
+	for _, l := range ov.Levels {
+		levels[l.Name] = l
+	}
+	for _, l := range m.Levels {
+		o, ok := levels[l.Name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("level %q: not in file", l.Name))
+			continue
+		}
+		delete(levels, l.Name)
+		if l.ZMin != o.ZMin {
+			diffs = append(diffs, fmt.Sprintf("level %q AltitudeMin: have %v, file has %v", l.Name, l.ZMin, o.ZMin))
+		}
+		if l.ZMax != o.ZMax {
+			diffs = append(diffs, fmt.Sprintf("level %q AltitudeMax: have %v, file has %v", l.Name, l.ZMax, o.ZMax))
+		}
+	}
+	for _, o := range ov.Levels {
+		if _, ok := levels[o.Name]; ok {
+			diffs = append(diffs, fmt.Sprintf("level %q: only in file", o.Name))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,73 @@
+package maps
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseOverview(t *testing.T) {
+	ov, err := ParseOverview(readFixture(t, "de_nuke.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ov.Name != "de_nuke" {
+		t.Errorf("name = %q, want de_nuke", ov.Name)
+	}
+	if want := (Meta{PosX: -3453, PosY: 2887, Scale: 7}); ov.Meta != want {
+		t.Errorf("meta = %+v, want %+v", ov.Meta, want)
+	}
+	want := []Level{
+		{Name: "default", ZMin: -495, ZMax: math.Inf(1)},
+		{Name: "lower", ZMin: math.Inf(-1), ZMax: -495},
+	}
+	if !reflect.DeepEqual(ov.Levels, want) {
+		t.Errorf("levels = %+v, want %+v", ov.Levels, want)
+	}
+
+	flat, err := ParseOverview([]byte(`"de_dust2" { "pos_x" "-2476" "pos_y" "3239" "scale" "4.4" }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flat.Levels) != 1 || flat.Levels[0].Name != "default" || !math.IsInf(flat.Levels[0].ZMin, -1) || !math.IsInf(flat.Levels[0].ZMax, 1) {
+		t.Errorf("single-level map: levels = %+v", flat.Levels)
+	}
+}
+
+func TestParseOverviewErrors(t *testing.T) {
+	for _, tt := range []struct{ name, src string }{
+		{"empty", `// nothing here`},
+		{"no scale", `"m" { "pos_x" "0" "pos_y" "0" }`},
+		{"zero scale", `"m" { "pos_x" "0" "pos_y" "0" "scale" "0" }`},
+		{"bad number", `"m" { "pos_x" "left" "pos_y" "0" "scale" "1" }`},
+		{"inverted section", `"m" { "pos_x" "0" "pos_y" "0" "scale" "1"
+			"verticalsections" { "default" { "AltitudeMax" "-10" "AltitudeMin" "10" } } }`},
+	} {
+		if _, err := ParseOverview([]byte(tt.src)); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	nuke, _ := NewRegistry().Get("de_nuke")
+	ov, err := ParseOverview(readFixture(t, "de_nuke.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := nuke.Diff(ov); len(d) != 0 {
+		t.Errorf("embedded de_nuke differs from its overview: %q", d)
+	}
+
+	ov.Meta.PosX = -3400
+	ov.Levels[1].ZMax = -500
+	ov.Levels = append(ov.Levels, Level{Name: "upper", ZMin: 100, ZMax: 200})
+	want := []string{
+		"pos_x: have -3453, file has -3400",
+		`level "lower" AltitudeMax: have -495, file has -500`,
+		`level "upper": only in file`,
+	}
+	if d := nuke.Diff(ov); !reflect.DeepEqual(d, want) {
+		t.Errorf("Diff = %q, want %q", d, want)
+	}
+}
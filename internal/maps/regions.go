@@ -0,0 +1,121 @@
+package maps
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// regions.json holds hand-traced region outlines per map, keyed by map name.
+// Bombsite and spawn polygons follow the coloured outlines on the radar images;
+// they are approximations in world units, good enough to tell which site a
+// player is on but not to the unit.
+//
+//go:embed regions.json
+var regionsJSON []byte
+
+// Region kinds.
+const (
+	RegionSite  = "site"  // a bombsite; Name is "A" or "B"
+	RegionSpawn = "spawn" // a team's spawn area
+	RegionArea  = "area"  // any other named area, e.g. "Mid"
+)
+
+// Region is a named polygon on a map's ground plane in world coordinates.
+// A position is inside when ZMin <= z < ZMax, which restricts the region to one
+// level of a multi-floor map; an unbounded end is ±Inf, as in Level. In JSON an
+// unbounded "zmin" or "zmax" is left out.
+type Region struct {
+	Name   string
+	Kind   string
+	ZMin   float64
+	ZMax   float64
+	Points [][2]float64
+}
+
+// regionJSON is Region's JSON form, with absent z bounds for ±Inf.
+type regionJSON struct {
+	Name   string       `json:"name"`
+	Kind   string       `json:"kind"`
+	ZMin   *float64     `json:"zmin,omitempty"`
+	ZMax   *float64     `json:"zmax,omitempty"`
+	Points [][2]float64 `json:"points"`
+}
+
+func (r Region) MarshalJSON() ([]byte, error) {
+	return json.Marshal(regionJSON{Name: r.Name, Kind: r.Kind, ZMin: finite(r.ZMin), ZMax: finite(r.ZMax), Points: r.Points})
+}
+
+func (r *Region) UnmarshalJSON(data []byte) error {
+	var j regionJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*r = Region{Name: j.Name, Kind: j.Kind, ZMin: math.Inf(-1), ZMax: math.Inf(1), Points: j.Points}
+	if j.ZMin != nil {
+		r.ZMin = *j.ZMin
+	}
+	if j.ZMax != nil {
+		r.ZMax = *j.ZMax
+	}
+	return nil
+}
+
+// finite returns &v, or nil for ±Inf.
+func finite(v float64) *float64 {
+	if math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+var regions map[string][]Region
+
+func init() {
+	if err := json.Unmarshal(regionsJSON, &regions); err != nil {
+		panic(fmt.Sprintf("maps: bad embedded regions.json: %v", err))
+	}
+}
+
+// Contains reports whether the world position (x, y, z) lies inside r.
+func (r Region) Contains(x, y, z float64) bool {
+	if z < r.ZMin || z >= r.ZMax {
+		return false
+	}
+	// Even-odd ray casting along +x.
+	in := false
+	for i, j := 0, len(r.Points)-1; i < len(r.Points); j, i = i, i+1 {
+		a, b := r.Points[i], r.Points[j]
+		if (a[1] > y) != (b[1] > y) && x < a[0]+(y-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+			in = !in
+		}
+	}
+	return in
+}
+
+// Regions returns the regions known for mapName, bombsites first; nil if none.
+func Regions(mapName string) []Region {
+	return regions[mapName]
+}
+
+// RegionAt returns the first region of mapName containing (x, y, z).
+// Bombsites are listed first, so they win over overlapping areas.
+func RegionAt(mapName string, x, y, z float64) (Region, bool) {
+	for _, r := range regions[mapName] {
+		if r.Contains(x, y, z) {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+// SiteAt returns the name of the bombsite of mapName containing (x, y, z), or "".
+func SiteAt(mapName string, x, y, z float64) string {
+	for _, r := range regions[mapName] {
+		if r.Kind == RegionSite && r.Contains(x, y, z) {
+			return r.Name
+		}
+	}
+	return ""
+}
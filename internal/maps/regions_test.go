@@ -0,0 +1,118 @@
+package maps
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestSiteAt(t *testing.T) {
+	tests := []struct {
+		mapName, what string
+		x, y, z       float64
+		want          string
+	}{
+		{"de_dust2", "B site", -1540, 2675, 0, "B"},
+		{"de_dust2", "A site", 1100, 2480, 0, "A"},
+		{"de_dust2", "mid", -320, 1000, 0, ""},
+		{"de_dust2", "CT spawn", 250, 2300, 0, ""},
+		{"de_mirage", "A bomb target", -445, -2190, 0, "A"},
+		{"de_mirage", "B site", -2050, 270, 0, "B"},
+		{"de_mirage", "T spawn", 1250, -100, 0, ""},
+		{"de_inferno", "A site", 2000, 450, 0, "A"},
+		{"de_inferno", "B site", 350, 2750, 0, "B"},
+		// Nuke's sites are stacked: the same x/y is A upstairs and B in the pit.
+		{"de_nuke", "A bomb target", 684, -717, -400, "A"},
+		{"de_nuke", "above B", 650, -1000, -400, "A"},
+		{"de_nuke", "B site", 650, -1000, -750, "B"},
+		{"de_nuke", "level boundary", 650, -1000, -495, "A"},
+		{"de_nuke", "just below the boundary", 650, -1000, -496, "B"},
+		{"de_nuke", "outside", 0, 0, -400, ""},
+		{"de_vertigo", "below the scaffold", -300, -630, 11600, ""},
+		{"de_vertigo", "A site", -300, -630, 11800, "A"},
+		{"de_unknown", "no regions", 0, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := SiteAt(tt.mapName, tt.x, tt.y, tt.z); got != tt.want {
+			t.Errorf("%s %s: SiteAt(%v, %v, %v) = %q, want %q", tt.mapName, tt.what, tt.x, tt.y, tt.z, got, tt.want)
+		}
+	}
+}
+
+// Every site's vertex centroid should be on that site and no other, and every
+// spawn's should be on no site.
+func TestSiteCentroids(t *testing.T) {
+	for name, regs := range regions {
+		for _, r := range regs {
+			var x, y float64
+			for _, p := range r.Points {
+				x += p[0] / float64(len(r.Points))
+				y += p[1] / float64(len(r.Points))
+			}
+			z := r.ZMin
+			if math.IsInf(z, -1) {
+				z = r.ZMax - 1
+			}
+			if math.IsInf(z, 1) {
+				z = 0
+			}
+			want := ""
+			if r.Kind == RegionSite {
+				want = r.Name
+			} else if r.Kind != RegionSpawn {
+				continue
+			}
+			if got := SiteAt(name, x, y, z); got != want {
+				t.Errorf("%s %s centroid (%.0f, %.0f, %.0f): SiteAt = %q, want %q", name, r.Name, x, y, z, got, want)
+			}
+		}
+	}
+}
+
+func TestRegionAt(t *testing.T) {
+	if r, ok := RegionAt("de_dust2", -320, 1000, 0); !ok || r.Name != "Mid" || r.Kind != RegionArea {
+		t.Errorf("dust2 mid: RegionAt = %+v, %v", r, ok)
+	}
+	if r, ok := RegionAt("de_mirage", 1250, -100, 0); !ok || r.Name != "T spawn" || r.Kind != RegionSpawn {
+		t.Errorf("mirage T spawn: RegionAt = %+v, %v", r, ok)
+	}
+	if r, ok := RegionAt("de_dust2", -1540, 2675, 0); !ok || r.Name != "B" {
+		t.Errorf("dust2 B: RegionAt = %+v, %v", r, ok)
+	}
+	if r, ok := RegionAt("de_dust2", -3000, -3000, 0); ok {
+		t.Errorf("off the map: RegionAt = %+v", r)
+	}
+}
+
+func TestRegionZBounds(t *testing.T) {
+	// A floor boundary at z=0: bounds are not a zero sentinel.
+	var r Region
+	if err := json.Unmarshal([]byte(`{"name":"pit","kind":"area","zmax":0,"points":[[0,0],[10,0],[10,10],[0,10]]}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(r.ZMin, -1) || r.ZMax != 0 {
+		t.Fatalf("bounds = %v..%v, want -Inf..0", r.ZMin, r.ZMax)
+	}
+	for _, tt := range []struct {
+		x, y, z float64
+		want    bool
+	}{
+		{5, 5, -1000, true},
+		{5, 5, -1, true},
+		{5, 5, 0, false},
+		{5, 5, 100, false},
+		{15, 5, -1, false},
+	} {
+		if got := r.Contains(tt.x, tt.y, tt.z); got != tt.want {
+			t.Errorf("Contains(%v, %v, %v) = %v, want %v", tt.x, tt.y, tt.z, got, tt.want)
+		}
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"name":"pit","kind":"area","zmax":0,"points":[[0,0],[10,0],[10,10],[0,10]]}`; string(b) != want {
+		t.Errorf("Marshal = %s, want %s", b, want)
+	}
+}
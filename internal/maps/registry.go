@@ -0,0 +1,180 @@
+package maps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Level is one vertical section of a map with its own radar image. A player at
+// height z is on the level when ZMin <= z < ZMax; the outermost levels are
+// unbounded (±Inf).
+type Level struct {
+	Name  string // verticalsections key; "default" is the map's main radar
+	ZMin  float64
+	ZMax  float64
+	Radar []byte // PNG
+}
+
+// Map is everything needed to draw one map: its coordinate metadata and levels.
+type Map struct {
+	Name   string
+	Meta   Meta
+	Levels []Level // highest first; a single unbounded level for one-floor maps
+}
+
+// Main returns the level drawn by default (named "default" in overview files).
+func (m *Map) Main() Level {
+	for _, l := range m.Levels {
+		if l.Name == "default" {
+			return l
+		}
+	}
+	return m.Levels[0]
+}
+
+// LevelAt returns the index in m.Levels of the level at height z, or -1 if the
+// levels leave a gap there.
+func (m *Map) LevelAt(z float64) int {
+	for i, l := range m.Levels {
+		if z >= l.ZMin && z < l.ZMax {
+			return i
+		}
+	}
+	return -1
+}
+
+// Registry is a set of maps by name: the embedded ones plus any loaded from disk.
+type Registry struct {
+	maps map[string]*Map
+}
+
+// NewRegistry returns a registry holding the embedded maps.
+func NewRegistry() *Registry {
+	r := &Registry{maps: map[string]*Map{}}
+	for name, meta := range metas {
+		levels := sections[name]
+		if levels == nil {
+			levels = []Level{{Name: "default", ZMin: math.Inf(-1), ZMax: math.Inf(1)}}
+		}
+		m := &Map{Name: name, Meta: meta, Levels: slices.Clone(levels)}
+		for i := range m.Levels {
+			radar, err := levelRadarPNG(name, m.Levels[i].Name)
+			if err != nil {
+				panic(fmt.Sprintf("maps: embedded map %s: %v", name, err))
+			}
+			m.Levels[i].Radar = radar
+		}
+		r.maps[name] = m
+	}
+	return r
+}
+
+// Get returns the map called name.
+func (r *Registry) Get(name string) (*Map, bool) {
+	m, ok := r.maps[name]
+	return m, ok
+}
+
+// Names returns the registered map names in sorted order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.maps))
+	for name := range r.maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadDir adds every overview in dir to the registry, replacing embedded maps of
+// the same name, and returns how many it loaded. An overview is a Valve
+// "<map>.txt" KeyValues file with its radar images next to it (see radarFiles).
+// A file that is not a valid overview or lacks a radar is skipped, leaving any
+// map of that name as it was; the errors of all skipped files are returned
+// together.
+func (r *Registry) LoadDir(dir string) (int, error) {
+	txts, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return 0, err
+	}
+	if len(txts) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return 0, err
+		}
+	}
+	var errs []error
+	n := 0
+	for _, path := range txts {
+		m, err := loadOverview(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		r.maps[m.Name] = m
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+// loadOverview reads one overview txt file and the radar images of its levels.
+func loadOverview(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ov, err := ParseOverview(data)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	m := &Map{Name: name, Meta: ov.Meta, Levels: ov.Levels}
+
+	dir := filepath.Dir(path)
+	for i := range m.Levels {
+		if m.Levels[i].Radar, err = loadRadar(dir, name, m.Levels[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// radarFiles lists the file names tried, in order, for a level's radar image:
+// the game's own "<map>_radar" and "<map>_radar_psd" names and the bare
+// "<map>.png" used in internal/maps/overviews. Levels other than "default"
+// insert their name after the map's, e.g. "de_nuke_lower_radar.dds".
+func radarFiles(mapName, level string) []string {
+	base := mapName
+	if level != "default" {
+		base += "_" + level
+	}
+	return []string{base + "_radar.png", base + "_radar_psd.png", base + ".png", base + "_radar.dds", base + ".dds"}
+}
+
+// loadRadar finds and reads a level's radar image, converting DDS to PNG.
+func loadRadar(dir, mapName, level string) ([]byte, error) {
+	names := radarFiles(mapName, level)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(name, ".dds") {
+			if data, err = ddsToPNG(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		} else if !bytes.HasPrefix(data, []byte("\x89PNG")) {
+			return nil, fmt.Errorf("%s: not a PNG file", name)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("no radar image for level %q (tried %s)", level, strings.Join(names, ", "))
+}
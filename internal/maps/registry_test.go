@@ -0,0 +1,133 @@
+package maps
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes a file into dir, failing the test on error.
+func writeFile(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tinyPNG returns a 1x1 PNG.
+func tinyPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	radar := tinyPNG(t)
+	// Overrides the embedded de_dust2.
+	writeFile(t, dir, "de_dust2.txt", []byte(`"de_dust2" { "pos_x" "-2400" "pos_y" "3300" "scale" "4.5" }`))
+	writeFile(t, dir, "de_dust2_radar.png", radar)
+	// A new two-level map with DDS radars.
+	writeFile(t, dir, "de_custom.txt", []byte(`"de_custom"
+{
+	"pos_x" "-1000" "pos_y" "1000" "scale" "2"
+	"verticalsections"
+	{
+		"default" { "AltitudeMax" "10000" "AltitudeMin" "-100" }
+		"lower"   { "AltitudeMax" "-100"  "AltitudeMin" "-10000" }
+	}
+}`))
+	dxt1 := ddsFile(4, 4, ddsPFFourCC, "DXT1", 0, [4]uint32{}, make([]byte, 8))
+	writeFile(t, dir, "de_custom_radar.dds", dxt1)
+	writeFile(t, dir, "de_custom_lower.dds", dxt1)
+
+	reg := NewRegistry()
+	n, err := reg.LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("loaded %d overviews, want 2", n)
+	}
+
+	d2, _ := reg.Get("de_dust2")
+	if want := (Meta{PosX: -2400, PosY: 3300, Scale: 4.5}); d2.Meta != want {
+		t.Errorf("de_dust2 meta = %+v, want %+v", d2.Meta, want)
+	}
+	if len(d2.Levels) != 1 || !bytes.Equal(d2.Levels[0].Radar, radar) {
+		t.Errorf("de_dust2 radar not taken from the directory")
+	}
+
+	c, ok := reg.Get("de_custom")
+	if !ok {
+		t.Fatalf("de_custom not registered; names %v", reg.Names())
+	}
+	if len(c.Levels) != 2 || c.Levels[0].Name != "default" || c.Levels[1].Name != "lower" {
+		t.Fatalf("de_custom levels = %+v", c.Levels)
+	}
+	for _, l := range c.Levels {
+		if !bytes.HasPrefix(l.Radar, []byte("\x89PNG")) {
+			t.Errorf("level %q radar was not converted to PNG", l.Name)
+		}
+	}
+	if got := c.LevelAt(-500); got != 1 {
+		t.Errorf("LevelAt(-500) = %d, want 1", got)
+	}
+	if !math.IsInf(c.Levels[0].ZMax, 1) || !math.IsInf(c.Levels[1].ZMin, -1) {
+		t.Errorf("outer levels are not open-ended: %+v", c.Levels)
+	}
+
+	// Embedded maps not in the directory are untouched.
+	if m, _ := reg.Get("de_mirage"); m.Meta != metas["de_mirage"] {
+		t.Errorf("de_mirage meta changed to %+v", m.Meta)
+	}
+}
+
+// Bad files are skipped without touching the maps they name, and the good
+// ones still load.
+func TestLoadDirSkipsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "de_empty.txt", []byte(`"de_empty" { "pos_x" "0" "pos_y" "0" "scale" "1" }`))
+	writeFile(t, dir, "de_mirage.txt", []byte(`"de_mirage" { "pos_x" "0" "pos_y" "0" }`))
+	writeFile(t, dir, "de_mirage_radar.png", tinyPNG(t))
+	writeFile(t, dir, "notes.txt", []byte(`not { an overview`))
+	writeFile(t, dir, "de_good.txt", []byte(`"de_good" { "pos_x" "0" "pos_y" "0" "scale" "1" }`))
+	writeFile(t, dir, "de_good.png", tinyPNG(t))
+
+	reg := NewRegistry()
+	n, err := reg.LoadDir(dir)
+	if n != 1 {
+		t.Errorf("loaded %d overviews, want 1", n)
+	}
+	if err == nil {
+		t.Fatal("no error for the bad files")
+	}
+	for _, want := range []string{"de_empty.txt", "de_empty_radar.png", "de_mirage.txt", "notes.txt"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to mention %s", err, want)
+		}
+	}
+	if _, ok := reg.Get("de_good"); !ok {
+		t.Errorf("de_good not registered")
+	}
+	if _, ok := reg.Get("de_empty"); ok {
+		t.Errorf("de_empty registered without a radar")
+	}
+	if m, _ := reg.Get("de_mirage"); m.Meta != metas["de_mirage"] {
+		t.Errorf("de_mirage replaced by a bad overview: %+v", m.Meta)
+	}
+}
+
+func TestLoadDirMissing(t *testing.T) {
+	if _, err := NewRegistry().LoadDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("no error for a missing directory")
+	}
+}
@@ -0,0 +1,57 @@
+package maps
+
+import "math"
+
+// RadarSize is the side in pixels of the radar image that overview metadata is
+// defined for. Radar coordinates below are in this space; multiply by
+// size/RadarSize to draw on an image of another size.
+const RadarSize = 1024
+
+// WorldToRadar converts world coordinates to radar pixels: x grows to the right
+// from PosX and the world Y axis is flipped, growing downwards from PosY.
+func (m Meta) WorldToRadar(x, y float64) (px, py float64) {
+	return (x - m.PosX) / m.Scale, (m.PosY - y) / m.Scale
+}
+
+// RadarToWorld is the inverse of WorldToRadar.
+func (m Meta) RadarToWorld(px, py float64) (x, y float64) {
+	return m.PosX + px*m.Scale, m.PosY - py*m.Scale
+}
+
+// UnitsToPixels converts a world distance (a smoke radius, say) to radar pixels.
+func (m Meta) UnitsToPixels(d float64) float64 {
+	return d / m.Scale
+}
+
+// PixelsToUnits converts a radar distance to world units.
+func (m Meta) PixelsToUnits(p float64) float64 {
+	return p * m.Scale
+}
+
+// WorldToRadar converts a world position to radar pixels on the level the
+// position is on, returned as an index into m.Levels (-1 if the levels leave a
+// gap at z). All of a map's levels share its Meta.
+func (m *Map) WorldToRadar(x, y, z float64) (px, py float64, level int) {
+	px, py = m.Meta.WorldToRadar(x, y)
+	return px, py, m.LevelAt(z)
+}
+
+// RadarToWorld converts radar pixels on the level at index level back to world
+// coordinates. z is a height on that level: the middle of a bounded range, else
+// just inside its finite bound, or 0 for an unbounded level.
+func (m *Map) RadarToWorld(px, py float64, level int) (x, y, z float64) {
+	x, y = m.Meta.RadarToWorld(px, py)
+	if level < 0 || level >= len(m.Levels) {
+		return x, y, 0
+	}
+	l := m.Levels[level]
+	switch lo, hi := !math.IsInf(l.ZMin, 0), !math.IsInf(l.ZMax, 0); {
+	case lo && hi:
+		z = (l.ZMin + l.ZMax) / 2
+	case lo:
+		z = l.ZMin
+	case hi:
+		z = l.ZMax - 1 // ZMax itself belongs to the level above
+	}
+	return x, y, z
+}
@@ -0,0 +1,110 @@
+package maps
+
+import (
+	"math"
+	"testing"
+)
+
+// Landmarks are world positions on a bombsite together with the pixel box of
+// that site's painted outline (or bomb target marker) on the embedded radar.
+var landmarks = []struct {
+	mapName, what string
+	x, y, z       float64
+	px0, py0      float64 // pixel box on the radar image
+	px1, py1      float64
+	level         string
+}{
+	{"de_dust2", "B site", -1540, 2675, 0, 170, 82, 255, 175, "default"},
+	{"de_dust2", "A site", 1100, 2480, 0, 780, 138, 845, 205, "default"},
+	{"de_mirage", "A bomb target", -445, -2190, 0, 519, 752, 595, 810, "default"},
+	{"de_mirage", "B site", -2050, 270, 0, 200, 250, 272, 327, "default"},
+	{"de_nuke", "A bomb target", 684, -717, -400, 565, 486, 617, 546, "default"},
+	{"de_nuke", "B site", 650, -1000, -750, 548, 512, 615, 595, "lower"},
+}
+
+func TestWorldToRadarLandmarks(t *testing.T) {
+	reg := NewRegistry()
+	for _, lm := range landmarks {
+		m, ok := reg.Get(lm.mapName)
+		if !ok {
+			t.Fatalf("%s: not registered", lm.mapName)
+		}
+		px, py, level := m.WorldToRadar(lm.x, lm.y, lm.z)
+		if px < lm.px0 || px > lm.px1 || py < lm.py0 || py > lm.py1 {
+			t.Errorf("%s %s: WorldToRadar(%v, %v) = (%.1f, %.1f), want within (%v..%v, %v..%v)",
+				lm.mapName, lm.what, lm.x, lm.y, px, py, lm.px0, lm.px1, lm.py0, lm.py1)
+		}
+		if level < 0 || m.Levels[level].Name != lm.level {
+			t.Errorf("%s %s: level %d at z=%v, want %q", lm.mapName, lm.what, level, lm.z, lm.level)
+		}
+	}
+}
+
+func TestRadarCorners(t *testing.T) {
+	m := metas["de_mirage"]
+	if px, py := m.WorldToRadar(m.PosX, m.PosY); px != 0 || py != 0 {
+		t.Errorf("origin maps to (%v, %v), want (0, 0)", px, py)
+	}
+	// The bottom-right corner is RadarSize pixels right and down: +x, -y in the world.
+	x, y := m.RadarToWorld(RadarSize, RadarSize)
+	if want := m.PosX + RadarSize*m.Scale; x != want {
+		t.Errorf("bottom-right x = %v, want %v", x, want)
+	}
+	if want := m.PosY - RadarSize*m.Scale; y != want {
+		t.Errorf("bottom-right y = %v, want %v", y, want)
+	}
+}
+
+func TestRadarRoundTrip(t *testing.T) {
+	for name, m := range metas {
+		for _, p := range [][2]float64{{0, 0}, {-1234.5, 987.25}, {3000, -3000}} {
+			px, py := m.WorldToRadar(p[0], p[1])
+			x, y := m.RadarToWorld(px, py)
+			if math.Abs(x-p[0]) > 1e-9 || math.Abs(y-p[1]) > 1e-9 {
+				t.Errorf("%s: (%v, %v) round-trips to (%v, %v)", name, p[0], p[1], x, y)
+			}
+		}
+	}
+}
+
+func TestUnitScale(t *testing.T) {
+	m := metas["de_mirage"] // scale 5
+	if got := m.UnitsToPixels(170); got != 34 {
+		t.Errorf("UnitsToPixels(170) = %v, want 34", got)
+	}
+	if got := m.PixelsToUnits(34); got != 170 {
+		t.Errorf("PixelsToUnits(34) = %v, want 170", got)
+	}
+}
+
+func TestRadarToWorldLevel(t *testing.T) {
+	m, _ := NewRegistry().Get("de_vertigo")
+	for i := range m.Levels {
+		_, _, z := m.RadarToWorld(512, 512, i)
+		if got := m.LevelAt(z); got != i {
+			t.Errorf("level %q: RadarToWorld gave z=%v, which is on level %d", m.Levels[i].Name, z, got)
+		}
+	}
+	d2, _ := NewRegistry().Get("de_dust2")
+	if _, _, z := d2.RadarToWorld(512, 512, 0); z != 0 {
+		t.Errorf("single-level map: z = %v, want 0", z)
+	}
+}
+
+// Every region outline should fall on its map's radar image.
+func TestRegionsOnRadar(t *testing.T) {
+	for name, regs := range regions {
+		m, ok := metas[name]
+		if !ok {
+			t.Errorf("regions for unknown map %s", name)
+			continue
+		}
+		for _, r := range regs {
+			for _, p := range r.Points {
+				if px, py := m.WorldToRadar(p[0], p[1]); px < 0 || py < 0 || px > RadarSize || py > RadarSize {
+					t.Errorf("%s %s: point %v is off the radar at (%.0f, %.0f)", name, r.Name, p, px, py)
+				}
+			}
+		}
+	}
+}
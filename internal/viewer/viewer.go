@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"strings"
 
 	"github.com/pable/cs-demo-viewer/internal/demo"
@@ -17,15 +18,21 @@ var templateHTML string
 
 // ViewerData is everything the HTML template needs.
 type ViewerData struct {
-	MapName    string            `json:"map"`
-	Meta       mapMeta           `json:"meta"`
-	Radar      string            `json:"radar"`       // "data:image/png;base64,..."
-	RadarLower string            `json:"radar_lower"` // "" if no lower level
-	HasLower   bool              `json:"has_lower"`
-	LowerZMax  float64           `json:"lower_z_max"` // z threshold for lower level
-	Players    []demo.PlayerInfo `json:"players"`
-	Rounds     []demo.Round      `json:"rounds"`
-	Stats      []demo.PlayerStat `json:"stats"` // parallel to Players
+	MapName     string            `json:"map"`
+	Meta        mapMeta           `json:"meta"`
+	Levels      []viewerLevel     `json:"levels"`       // vertical sections, highest first; one for single-level maps
+	MainLevel   int               `json:"main_level"`   // index into Levels shown at start
+	TickRate    float64           `json:"tick_rate"`    // game ticks per second, for tick → time conversion
+	SampleTicks int               `json:"sample_ticks"` // ticks between frames, drives playback rate
+	PSVersion   int               `json:"ps_version"`   // PlayerState array layout
+	RoundTime   float64           `json:"round_time"`   // seconds of play per round (0 = unknown, viewer assumes 115)
+	C4Time      float64           `json:"c4_time"`      // bomb fuse seconds (0 = unknown, viewer assumes 40)
+	Teams       [2]demo.Team      `json:"teams"`
+	Places      []string          `json:"places"`  // callout names, indexed by PlayerState/Kill/BombAction/Grenade place
+	Regions     []maps.Region     `json:"regions"` // bombsite, spawn and area outlines; may be empty
+	Players     []demo.PlayerInfo `json:"players"`
+	Rounds      []demo.Round      `json:"rounds"`
+	Stats       []demo.PlayerStat `json:"stats"` // parallel to Players
 }
 
 type mapMeta struct {
@@ -34,24 +41,54 @@ type mapMeta struct {
 	Scale float64 `json:"scale"`
 }
 
-// Write generates the self-contained HTML viewer and writes it to w.
-func Write(w io.Writer, d *demo.DemoData, meta maps.Meta, radarPNG []byte, radarLowerPNG []byte, lower maps.Lower, hasLower bool) error {
+// viewerLevel is a maps.Level for the template. A player at height z is on the
+// level when z_min <= z < z_max; null bounds are open (JSON has no infinity).
+type viewerLevel struct {
+	Name  string   `json:"name"`
+	ZMin  *float64 `json:"z_min"`
+	ZMax  *float64 `json:"z_max"`
+	Radar string   `json:"radar"` // "data:image/png;base64,..."
+}
+
+// finite returns &v, or nil for ±Inf.
+func finite(v float64) *float64 {
+	if math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// Write generates the self-contained HTML viewer for a demo played on m and writes it to w.
+func Write(w io.Writer, d *demo.DemoData, m *maps.Map) error {
 	vd := ViewerData{
 		MapName: d.MapName,
 		Meta: mapMeta{
-			PosX:  meta.PosX,
-			PosY:  meta.PosY,
-			Scale: meta.Scale,
+			PosX:  m.Meta.PosX,
+			PosY:  m.Meta.PosY,
+			Scale: m.Meta.Scale,
 		},
-		Radar:    "data:image/png;base64," + base64.StdEncoding.EncodeToString(radarPNG),
-		Players:  d.Players,
-		Rounds:   d.Rounds,
-		Stats:    d.Stats,
-		HasLower: hasLower,
+		TickRate:    d.TickRate,
+		SampleTicks: d.SampleTicks,
+		PSVersion:   d.PSVersion,
+		RoundTime:   d.RoundTime,
+		Teams:       d.Teams,
+		Places:      d.Places,
+		Regions:     maps.Regions(d.MapName),
+		C4Time:      d.C4Time,
+		Players:     d.Players,
+		Rounds:      d.Rounds,
+		Stats:       d.Stats,
 	}
-	if hasLower && radarLowerPNG != nil {
-		vd.RadarLower = "data:image/png;base64," + base64.StdEncoding.EncodeToString(radarLowerPNG)
-		vd.LowerZMax = lower.ZMax
+	for i, l := range m.Levels {
+		if l.Name == m.Main().Name {
+			vd.MainLevel = i
+		}
+		vd.Levels = append(vd.Levels, viewerLevel{
+			Name:  l.Name,
+			ZMin:  finite(l.ZMin),
+			ZMax:  finite(l.ZMax),
+			Radar: "data:image/png;base64," + base64.StdEncoding.EncodeToString(l.Radar),
+		})
 	}
 
 	jsonBytes, err := json.Marshal(vd)
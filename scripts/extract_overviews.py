@@ -2,7 +2,7 @@
 """Extract CS2 map radar PNGs from the game's VPK archive.
 
 Usage:
-    python3 extract_overviews.py [--game-dir <path>] [--out <dir>]
+    python3 extract_overviews.py [--game-dir <path>] [--out <dir>] [--txt-out <dir>]
 
 Defaults:
     game-dir: /mnt/c/Program Files (x86)/Steam/steamapps/common/Counter-Strike Global Offensive
@@ -10,6 +10,10 @@ Defaults:
 
 Run this when Valve updates a map's radar image. After running, rebuild the binary
 so the new PNGs are embedded via go:embed.
+
+With --txt-out the overview txt files are saved too; check the embedded metadata
+against them with:
+    demoview maps validate <dir>/*.txt
 """
 
 import argparse
@@ -112,11 +116,15 @@ def main():
     parser = argparse.ArgumentParser(description=__doc__, formatter_class=argparse.RawDescriptionHelpFormatter)
     parser.add_argument("--game-dir", default=DEFAULT_GAME_DIR)
     parser.add_argument("--out", default=str(DEFAULT_OUT_DIR))
+    parser.add_argument("--txt-out", help="also save resource/overviews/<map>.txt files here")
     args = parser.parse_args()
 
     game_dir = Path(args.game_dir)
     out_dir  = Path(args.out)
     out_dir.mkdir(parents=True, exist_ok=True)
+    txt_dir = Path(args.txt_out) if args.txt_out else None
+    if txt_dir:
+        txt_dir.mkdir(parents=True, exist_ok=True)
 
     vpk_path = str(game_dir / "game" / "csgo" / "pak01_dir.vpk")
 
@@ -136,6 +144,8 @@ def main():
             mapname = key[5:]
             meta = parse_overview_meta(data)
             print(f"  {mapname}: {meta}")
+            if txt_dir:
+                (txt_dir / f"{mapname}.txt").write_bytes(data)
         else:
             img = decode_vtex(data)
             out_path = out_dir / f"{key}.png"